@@ -1,24 +1,71 @@
 package main
 
 import (
+	"context"
 	"final_project/pkg/db"
 	"final_project/pkg/server"
+	"fmt"
 	"log"
 	"os"
+	// Встроенная база часовых поясов для TODO_TZ и X-Timezone в окружениях без tzdata
+	_ "time/tzdata"
 )
 
 func main() {
+	// Подкоманда migrate работает со схемой БД без запуска HTTP-сервера
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Allow switching web root via env if needed in future; default to ./web
 	webDir := "./web"
 	if v := os.Getenv("WEB_DIR"); v != "" {
 		webDir = v
 	}
 
-	if err := db.Init(); err != nil {
+	store, err := db.Init()
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := server.Run(webDir); err != nil {
+	if err := server.Run(context.Background(), webDir, store); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// runMigrate выполняет подкоманды "migrate status" и "migrate up"
+func runMigrate(args []string) error {
+	if len(args) != 1 || (args[0] != "status" && args[0] != "up") {
+		return fmt.Errorf("использование: %s migrate status|up", os.Args[0])
+	}
+
+	conn, err := db.Open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if args[0] == "up" {
+		applied, err := db.Migrate(conn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("применено миграций: %d\n", applied)
+	}
+
+	status, err := db.Status(conn)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("текущая версия схемы: %d, последняя: %d\n", status.Current, status.Latest)
+	for _, m := range status.Pending {
+		fmt.Printf("  ожидает: %d %s\n", m.Version, m.Name)
+	}
+	if status.Current > status.Latest {
+		fmt.Println("  БД создана более новой версией программы")
+	}
+	return nil
+}
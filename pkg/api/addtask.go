@@ -9,31 +9,63 @@ import (
 )
 
 // addTaskHandler обрабатывает POST-запросы для добавления задач
-func addTaskHandler(w http.ResponseWriter, r *http.Request) {
-	var task db.Task
-
-	// Десериализуем JSON
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		writeJson(w, map[string]string{"error": "ошибка десериализации JSON"}, http.StatusBadRequest)
+func (h *Handler) addTaskHandler(w http.ResponseWriter, r *http.Request) {
+	// Десериализуем JSON; тело разбирается так же, как при обновлении,
+	// отсутствующие необязательные поля получают нулевые значения
+	var req taskUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, db.Invalid("", "ошибка десериализации JSON"))
+		return
+	}
+	task, _ := req.task()
+	if err := checkRepeatRule(&task, req.Repeat); err != nil {
+		writeError(w, err)
 		return
 	}
 
+	// Счётчик оставшихся повторений ведёт сервер, новая задача начинает серию с начала
+	task.Remaining = 0
+
 	// Проверяем обязательное поле title
 	if task.Title == "" {
-		writeJson(w, map[string]string{"error": "Не указан заголовок задачи"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("title", "Не указан заголовок задачи"))
+		return
+	}
+
+	// Проверяем и корректируем дату относительно «сегодня» в часовом поясе пользователя
+	now, err := h.requestNow(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.checkDate(&task, now); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем время и длительность
+	if err := checkTime(&task); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем метки
+	if err := checkTags(&task); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Проверяем и корректируем дату
-	if err := checkDate(&task); err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+	// Проверяем приоритет
+	if err := checkPriority(&task); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Добавляем задачу в базу данных
-	id, err := db.AddTask(&task)
+	// Добавляем задачу в базу данных с моментом создания по часам сервера
+	task.Created = now.UTC().Format(db.DoneAtFormat)
+	id, err := h.store.AddTask(&task)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+		writeError(w, err)
 		return
 	}
 
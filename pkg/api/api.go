@@ -2,29 +2,66 @@ package api
 
 import (
 	"net/http"
+
+	"final_project/pkg/db"
 )
 
-// Init регистрирует все API обработчики
-// Эта функция должна вызываться из server.Run() до запуска сервера
-func Init() {
-	http.HandleFunc("/api/nextdate", NextDateHandler)
-	http.HandleFunc("/api/task", taskHandler)
-	http.HandleFunc("/api/tasks", tasksHandler)
-	http.HandleFunc("/api/task/done", taskDoneHandler)
+// Handler обрабатывает запросы к API
+// Зависимости обработчиков (хранилище и часы) передаются через поля, а не берутся из глобального состояния,
+// поэтому в одном процессе можно создать несколько независимых обработчиков
+type Handler struct {
+	store Store
+	clock Clock
+	mux   *http.ServeMux
+}
+
+// NewHandler создаёт обработчик API с указанными хранилищем и часами
+// Все обработчики, кроме /api/signin и ленты календаря, защищены middleware auth
+// Лента календаря проверяет собственный секрет, так как клиенты не передают cookie
+func NewHandler(store Store, clock Clock) *Handler {
+	h := &Handler{store: store, clock: clock, mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("/api/signin", h.signInHandler)
+	h.mux.HandleFunc("/api/calendar.ics", h.calendarHandler)
+	h.mux.HandleFunc("/api/nextdate", h.auth(h.NextDateHandler))
+	h.mux.HandleFunc("/api/occurrences", h.auth(h.occurrencesHandler))
+	h.mux.HandleFunc("/api/task", h.auth(h.taskHandler))
+	h.mux.HandleFunc("/api/tasks", h.auth(h.tasksHandler))
+	h.mux.HandleFunc("/api/tags", h.auth(h.tagsHandler))
+	h.mux.HandleFunc("/api/task/done", h.auth(h.taskDoneHandler))
+	h.mux.HandleFunc("/api/task/undo", h.auth(h.undoHandler))
+	h.mux.HandleFunc("/api/task/history", h.auth(h.taskHistoryHandler))
+	h.mux.HandleFunc("/api/completions", h.auth(h.completionsHandler))
+	h.mux.HandleFunc("/api/import/ics", h.auth(h.importICSHandler))
+	h.mux.HandleFunc("/api/export", h.auth(h.exportHandler))
+	h.mux.HandleFunc("/api/import", h.auth(h.importHandler))
+	h.mux.HandleFunc("/api/holidays", h.auth(h.holidaysHandler))
+
+	// Неизвестные пути API тоже получают ответ в формате ErrorResp
+	h.mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, db.NotFound("метод API не найден: %s", r.URL.Path))
+	})
+
+	return h
+}
+
+// ServeHTTP передаёт запрос обработчику, зарегистрированному для его пути
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
 }
 
 // taskHandler обрабатывает запросы к /api/task в зависимости от HTTP-метода
-func taskHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) taskHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		addTaskHandler(w, r)
+		h.addTaskHandler(w, r)
 	case http.MethodGet:
-		getTaskHandler(w, r)
+		h.getTaskHandler(w, r)
 	case http.MethodPut:
-		updateTaskHandler(w, r)
+		h.updateTaskHandler(w, r)
 	case http.MethodDelete:
-		deleteTaskHandler(w, r)
+		h.deleteTaskHandler(w, r)
 	default:
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 	}
 }
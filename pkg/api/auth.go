@@ -0,0 +1,166 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// Константы для аутентификации
+const (
+	envPasswordKey  = "TODO_PASSWORD" // имя переменной окружения с паролем
+	tokenCookieName = "token"         // имя cookie, в которой фронтенд хранит токен
+	tokenTTL        = 8 * time.Hour   // время жизни токена, совпадает со сроком cookie во фронтенде
+)
+
+// SignInReq представляет тело запроса к /api/signin
+type SignInReq struct {
+	Password string `json:"password"`
+}
+
+// tokenHeader — заголовок JWT, подписываем только HS256
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// tokenClaims — полезная нагрузка токена
+// Пароль в токен не попадает: токен подписан ключом, выведенным из пароля,
+// поэтому после смены пароля старые токены не проходят проверку подписи
+type tokenClaims struct {
+	Exp int64 `json:"exp"`
+}
+
+// getPassword возвращает пароль из переменной окружения TODO_PASSWORD
+// Пустая строка означает, что аутентификация отключена
+func getPassword() string {
+	return os.Getenv(envPasswordKey)
+}
+
+// passwordHash возвращает хэш пароля в шестнадцатеричном виде
+func passwordHash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// sign вычисляет HMAC-SHA256 подпись данных, ключом служит хэш пароля
+func sign(data, password string) string {
+	mac := hmac.New(sha256.New, []byte(passwordHash(password)))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newToken формирует подписанный JWT для указанного пароля
+func newToken(password string, now time.Time) (string, error) {
+	header, err := json.Marshal(tokenHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(tokenClaims{
+		Exp: now.Add(tokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + sign(unsigned, password), nil
+}
+
+// verifyToken проверяет подпись и срок действия токена
+func verifyToken(token, password string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("некорректный формат токена")
+	}
+
+	unsigned := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(unsigned, password))) {
+		return fmt.Errorf("неверная подпись токена")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("некорректный формат токена")
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return fmt.Errorf("некорректный формат токена")
+	}
+
+	if now.Unix() > claims.Exp {
+		return fmt.Errorf("срок действия токена истёк")
+	}
+	return nil
+}
+
+// signInHandler обрабатывает POST-запросы к /api/signin
+// Сверяет пароль с TODO_PASSWORD и возвращает подписанный токен
+func (h *Handler) signInHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это POST-запрос
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	var req SignInReq
+
+	// Десериализуем JSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, db.Invalid("", "ошибка десериализации JSON"))
+		return
+	}
+
+	// Сверяем пароль, сравнение выполняем за постоянное время
+	password := getPassword()
+	if password == "" || !hmac.Equal([]byte(req.Password), []byte(password)) {
+		writeErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "Неверный пароль")
+		return
+	}
+
+	// Формируем токен
+	token, err := newToken(password, h.clock.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, map[string]string{"token": token}, http.StatusOK)
+}
+
+// auth создаёт middleware, проверяющий токен из cookie
+// Если пароль не задан, запросы пропускаются без проверки
+func (h *Handler) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		password := getPassword()
+		if password == "" {
+			next(w, r)
+			return
+		}
+
+		// Получаем токен из cookie
+		cookie, err := r.Cookie(tokenCookieName)
+		if err != nil {
+			writeErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "Требуется аутентификация")
+			return
+		}
+
+		// Проверяем токен
+		if err := verifyToken(cookie.Value, password, h.clock.Now()); err != nil {
+			writeErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+			return
+		}
+
+		next(w, r)
+	}
+}
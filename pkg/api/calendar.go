@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/hmac"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"final_project/pkg/db"
+	"final_project/pkg/ical"
+)
+
+// envCalendarTokenKey — имя переменной окружения с секретом ленты календаря
+const envCalendarTokenKey = "TODO_CALENDAR_TOKEN"
+
+// calendarUID формирует постоянный UID события по id задачи
+// Одинаковый UID при повторной подписке не даёт клиенту дублировать события
+func calendarUID(id string) string {
+	return "task-" + id + "@final_project"
+}
+
+// checkFeedToken проверяет секрет ленты из параметра token
+// Календарные клиенты не передают cookie, поэтому лента защищена отдельным секретом
+// Если секрет не задан, лента открыта только при отключённой аутентификации
+// Возвращает статус, код ErrorResp и текст ошибки; при успехе статус равен 200
+func checkFeedToken(r *http.Request) (int, string, string) {
+	secret := os.Getenv(envCalendarTokenKey)
+	if secret == "" {
+		if getPassword() != "" {
+			return http.StatusForbidden, CodeForbidden, "Лента календаря отключена: не задан TODO_CALENDAR_TOKEN"
+		}
+		return http.StatusOK, "", ""
+	}
+	if !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(secret)) {
+		return http.StatusUnauthorized, CodeUnauthorized, "Неверный токен ленты календаря"
+	}
+	return http.StatusOK, "", ""
+}
+
+// calendarHandler обрабатывает GET-запросы к /api/calendar.ics
+// Отдаёт все задачи как события iCalendar с правилами повторения RRULE
+func (h *Handler) calendarHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	if status, code, msg := checkFeedToken(r); status != http.StatusOK {
+		writeErrorCode(w, status, code, msg)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+
+	cw := ical.NewWriter(w, h.clock.Now())
+	if err := cw.Begin("Планировщик задач"); err != nil {
+		return
+	}
+
+	err := h.store.EachTask(func(task *db.Task) error {
+		// Задача со временем выгружается как событие с началом и длительностью
+		layout, value := DateFormat, task.Date
+		if task.Time != "" {
+			layout, value = DateFormat+" "+TimeFormat, task.Date+" "+task.Time
+		}
+		date, err := time.Parse(layout, value)
+		if err != nil {
+			// Задачу с некорректной датой пропускаем, чтобы не испортить всю ленту
+			log.Printf("calendar: задача %s: некорректная дата %q", task.ID, task.Date)
+			return nil
+		}
+
+		// Правило без аналога в RRULE экспортируем как разовое событие
+		rrule, err := ical.RRule(task.Repeat, task.Remaining)
+		if err != nil {
+			log.Printf("calendar: задача %s: %v", task.ID, err)
+		}
+
+		return cw.WriteEvent(ical.Event{
+			UID:         calendarUID(task.ID),
+			Date:        date,
+			HasTime:     task.Time != "",
+			Duration:    time.Duration(task.Duration) * time.Minute,
+			Summary:     task.Title,
+			Description: task.Comment,
+			RRule:       rrule,
+		})
+	})
+	if err != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		log.Printf("calendar: %v", err)
+		return
+	}
+
+	cw.End()
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// envFakeNowKey — переменная окружения, останавливающая часы сервера на заданном моменте
+// Предназначена только для тестов: все обработчики видят одно и то же «сейчас»
+const envFakeNowKey = "TODO_FAKE_NOW"
+
+// Clock — источник текущего времени для обработчиков API
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock возвращает текущее системное время
+type SystemClock struct{}
+
+// Now возвращает time.Now()
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock всегда возвращает один и тот же момент времени
+type FixedClock struct {
+	T time.Time
+}
+
+// Now возвращает зафиксированный момент
+func (c FixedClock) Now() time.Time {
+	return c.T
+}
+
+// ClockFromEnv возвращает часы, остановленные на TODO_FAKE_NOW, или системные часы,
+// если переменная не задана. Значение — момент RFC 3339 или дата 20060102;
+// дата означает полдень этого дня в часовом поясе TODO_TZ, чтобы «сегодня» не зависело
+// от часового пояса клиента в пределах нескольких часов
+func ClockFromEnv() (Clock, error) {
+	v := os.Getenv(envFakeNowKey)
+	if v == "" {
+		return SystemClock{}, nil
+	}
+	if t, err := time.ParseInLocation(DateFormat, v, defaultLocation()); err == nil {
+		return FixedClock{T: t.Add(12 * time.Hour)}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s должна быть датой 20060102 или моментом RFC 3339, получено: %s", envFakeNowKey, v)
+	}
+	return FixedClock{T: t}, nil
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"fmt"
 	"time"
 
 	"final_project/pkg/db"
@@ -11,9 +10,30 @@ import (
 // checkDate проверяет и корректирует дату задачи
 // Если дата пустая - устанавливает текущую дату
 // Если дата в прошлом и есть правило повторения - вычисляет следующую дату
+// (при переносе на рабочий день дата до переноса запоминается в task.Anchor)
 // Если дата в прошлом и нет правила повторения - устанавливает текущую дату
-func checkDate(task *db.Task) error {
-	now := time.Now()
+// Правило повторения проверяется всегда; также заполняются repeat_rule
+// и счётчик оставшихся повторений: заданный счётчик (например, из файла импорта)
+// сохраняется, если не превышает count правила, нулевой становится равным count
+// now — текущее время в часовом поясе пользователя (см. requestNow)
+func (h *Handler) checkDate(task *db.Task, now time.Time) error {
+	// Проверяем правило независимо от даты, чтобы некорректное правило не попало в БД
+	rule, err := nextdate.Parse(task.Repeat)
+	if err != nil {
+		return db.Invalid("repeat", "правило повторения указано в неправильном формате: %v", err)
+	}
+	task.RepeatRule = rule
+
+	// Счётчик повторений из условия count хранится вместе с задачей
+	switch {
+	case rule == nil || rule.Count == 0:
+		task.Remaining = 0
+	case task.Remaining == 0:
+		task.Remaining = rule.Count
+	case task.Remaining < 0 || task.Remaining > rule.Count:
+		return db.Invalid("remaining", "осталось повторений должно быть от 1 до %d, получено: %d",
+			rule.Count, task.Remaining)
+	}
 
 	// Если дата не указана, используем текущую дату
 	if task.Date == "" {
@@ -24,27 +44,87 @@ func checkDate(task *db.Task) error {
 	// Проверяем корректность формата даты
 	t, err := time.Parse(DateFormat, task.Date)
 	if err != nil {
-		return fmt.Errorf("дата представлена в формате, отличном от 20060102")
+		return db.Invalid("date", "дата представлена в формате, отличном от 20060102")
 	}
 
 	// Если дата в прошлом
 	if afterNow(now, t) {
-		if task.Repeat == "" {
+		if rule == nil {
 			// Если правила повторения нет, используем сегодняшнюю дату
 			task.Date = now.Format(DateFormat)
 		} else {
-			// Если есть правило повторения, вычисляем следующую дату
-			next, err := nextdate.NextDate(now, task.Date, task.Repeat)
+			// Если есть правило повторения, вычисляем следующую дату с учётом праздников
+			cal, err := h.workCalendar()
 			if err != nil {
-				return fmt.Errorf("правило повторения указано в неправильном формате: %w", err)
+				return err
 			}
-			task.Date = next
+			next, anchor, err := rule.NextShifted(now, task.Date, "", cal)
+			if err != nil {
+				return db.Invalid("repeat", "правило повторения указано в неправильном формате: %v", err)
+			}
+			task.Date, task.Anchor = next, anchor
 		}
 	}
 
 	return nil
 }
 
+// checkRepeatRule выбирает правило повторения из полей repeat и repeat_rule запроса
+// repeat — значение поля repeat или nil, если поля нет в запросе
+// Без поля repeat правило берётся из repeat_rule. Если указаны оба поля, они должны задавать
+// одно правило: иначе непонятно, какое из них сохранить (например, клиент очистил repeat,
+// а repeat_rule из ответа GET оставил)
+func checkRepeatRule(task *db.Task, repeat *string) error {
+	if repeat != nil {
+		task.Repeat = *repeat
+	}
+	if task.RepeatRule == nil {
+		return nil
+	}
+	if repeat == nil {
+		task.Repeat = task.RepeatRule.String()
+		return nil
+	}
+
+	rule, err := nextdate.Parse(task.Repeat)
+	if err != nil {
+		return db.Invalid("repeat", "правило повторения указано в неправильном формате: %v", err)
+	}
+	if rule.String() != task.RepeatRule.String() {
+		return db.Invalid("repeat_rule", "правило repeat_rule не совпадает с repeat %q", task.Repeat)
+	}
+	return nil
+}
+
+// TimeFormat — формат времени начала задачи
+const TimeFormat = "15:04"
+
+// maxDuration — максимальная длительность задачи в минутах (сутки)
+const maxDuration = 24 * 60
+
+// checkTime проверяет время начала и длительность задачи
+// Время приводится к формату 15:04; без времени задача считается задачей на весь день,
+// и длительность для неё не указывается
+func checkTime(task *db.Task) error {
+	if task.Time == "" {
+		if task.Duration != 0 {
+			return db.Invalid("duration", "длительность указывается только вместе со временем задачи")
+		}
+		return nil
+	}
+
+	t, err := time.Parse(TimeFormat, task.Time)
+	if err != nil {
+		return db.Invalid("time", "время представлено в формате, отличном от 15:04")
+	}
+	task.Time = t.Format(TimeFormat)
+
+	if task.Duration < 0 || task.Duration > maxDuration {
+		return db.Invalid("duration", "длительность должна быть от 0 до %d минут, получено: %d", maxDuration, task.Duration)
+	}
+	return nil
+}
+
 // afterNow проверяет, что первая дата больше второй (игнорируя время)
 func afterNow(date, now time.Time) bool {
 	// Нормализуем даты, убирая время
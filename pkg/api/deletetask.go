@@ -7,27 +7,40 @@ import (
 )
 
 // deleteTaskHandler обрабатывает DELETE-запросы для удаления задачи
-func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это DELETE-запрос
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
 	// Получаем параметр id из URL
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("id", "Не указан идентификатор"))
 		return
 	}
 
-	// Удаляем задачу из базы данных
-	err := db.DeleteTask(id)
+	// Задача могла измениться после того, как клиент её прочитал
+	version, err := h.ifMatchVersion(r, id)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+		writeError(w, err)
 		return
 	}
 
+	undo, err := newUndo(h.clock.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Задача удаляется вместе с сохранением снимка для отмены в одной транзакции
+	if err := h.store.DeleteTask(id, version, undo); err != nil {
+		writeError(w, err)
+		return
+	}
+	setUndoHeader(w, undo)
+
 	// Возвращаем пустой JSON при успешном удалении
 	writeJson(w, map[string]interface{}{}, http.StatusOK)
 }
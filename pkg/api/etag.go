@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"final_project/pkg/db"
+)
+
+// ifMatchHeader — заголовок запроса с ожидаемой версией задачи
+const ifMatchHeader = "If-Match"
+
+// taskETag возвращает значение заголовка ETag для версии задачи
+func taskETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// setTaskETag передаёт версию задачи в заголовке ETag ответа
+func setTaskETag(w http.ResponseWriter, task *db.Task) {
+	w.Header().Set("ETag", taskETag(task.Version))
+}
+
+// noVersion — версия, которой не бывает у задачи; с ней операция завершается ошибкой
+// ErrVersionMismatch, если задача существует
+const noVersion = -1
+
+// errIfMatch — ошибка разбора заголовка If-Match
+var errIfMatch = db.Invalid(ifMatchHeader, "заголовок If-Match должен содержать ETag задачи, например \"3\"")
+
+// ifMatchVersion разбирает заголовок If-Match со списком ETag из GET /api/task
+// и возвращает версию, которую должна иметь задача id
+// Возвращает 0, если заголовок не указан или равен *, — тогда версия задачи не проверяется
+// ETag сравниваются строго (RFC 7232, раздел 3.1): слабый ETag W/"3" не совпадает ни с одной версией
+// Если в списке несколько ETag, выбирается совпадающий с текущей версией задачи;
+// если задачу изменят сразу после этого, операция вернёт ErrVersionMismatch
+func (h *Handler) ifMatchVersion(r *http.Request, id string) (int, error) {
+	v := strings.TrimSpace(r.Header.Get(ifMatchHeader))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	versions, err := parseIfMatch(v)
+	if err != nil {
+		return 0, err
+	}
+
+	switch len(versions) {
+	case 0:
+		return noVersion, nil
+	case 1:
+		return versions[0], nil
+	}
+	task, err := h.store.GetTask(id)
+	if err != nil {
+		return 0, err
+	}
+	if slices.Contains(versions, task.Version) {
+		return task.Version, nil
+	}
+	return noVersion, nil
+}
+
+// parseIfMatch разбирает список ETag через запятую и возвращает версии из сильных ETag
+// Слабые ETag и ETag, не являющиеся версией задачи, пропускаются: они не совпадают ни с одной версией
+func parseIfMatch(v string) ([]int, error) {
+	var versions []int
+	for rest := v; ; {
+		rest = strings.TrimLeft(rest, " \t")
+		weak := strings.HasPrefix(rest, "W/")
+		if weak {
+			rest = rest[len("W/"):]
+		}
+		// Значение ETag — строка в кавычках, которая сама может содержать запятые
+		if len(rest) < 2 || rest[0] != '"' {
+			return nil, errIfMatch
+		}
+		end := strings.IndexByte(rest[1:], '"') + 1
+		if end == 0 {
+			return nil, errIfMatch
+		}
+		if version, err := strconv.Atoi(rest[1:end]); err == nil && version > 0 && !weak {
+			versions = append(versions, version)
+		}
+
+		rest = strings.TrimLeft(rest[end+1:], " \t")
+		if rest == "" {
+			return versions, nil
+		}
+		if rest[0] != ',' {
+			return nil, errIfMatch
+		}
+		rest = rest[1:]
+	}
+}
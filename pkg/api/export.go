@@ -0,0 +1,344 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// Форматы экспорта и импорта задач
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// csvHeader — заголовок CSV-файла, колонки совпадают с полями JSON задачи
+// Метки задачи записываются в колонку tags одной строкой через db.TagSeparator
+var csvHeader = []string{
+	"id", "date", "title", "comment", "repeat", "time", "duration", "priority", "created", "tags", "remaining",
+}
+
+// ImportRow описывает результат проверки одной строки импорта
+type ImportRow struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ImportResp представляет ответ API импорта задач
+// Если хотя бы одна строка не прошла проверку, заполняются также поля ErrorResp code и error
+type ImportResp struct {
+	Code     string      `json:"code,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Mode     string      `json:"mode"`
+	Imported int         `json:"imported"`
+	Errors   int         `json:"errors"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+// exportFormat возвращает формат из параметра format (по умолчанию json)
+func exportFormat(r *http.Request) (string, error) {
+	switch f := r.URL.Query().Get("format"); f {
+	case "", formatJSON:
+		return formatJSON, nil
+	case formatCSV:
+		return formatCSV, nil
+	default:
+		return "", db.Invalid("format", "параметр format должен быть csv или json")
+	}
+}
+
+// exportHandler обрабатывает GET-запросы к /api/export?format=csv|json
+// Потоково отдаёт все задачи; JSON имеет тот же вид, что и ответ /api/tasks
+func (h *Handler) exportHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if format == formatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="scheduler.csv"`)
+		err = h.exportCSV(w)
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="scheduler.json"`)
+		err = h.exportJSON(w)
+	}
+	if err != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		log.Printf("export: %v", err)
+	}
+}
+
+// exportCSV записывает все задачи в формате CSV с заголовком
+func (h *Handler) exportCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	err := h.store.EachTask(func(task *db.Task) error {
+		duration := ""
+		if task.Duration > 0 {
+			duration = strconv.Itoa(task.Duration)
+		}
+		remaining := ""
+		if task.Remaining > 0 {
+			remaining = strconv.Itoa(task.Remaining)
+		}
+		priority := ""
+		if task.Priority != db.PriorityNone {
+			priority = strconv.Itoa(task.Priority)
+		}
+		return cw.Write([]string{task.ID, task.Date, task.Title, task.Comment, task.Repeat, task.Time, duration,
+			priority, task.Created, strings.Join(task.Tags, db.TagSeparator), remaining})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportJSON записывает все задачи в виде {"tasks": [...]}
+func (h *Handler) exportJSON(w io.Writer) error {
+	if _, err := io.WriteString(w, `{"tasks":[`); err != nil {
+		return err
+	}
+	first := true
+	err := h.store.EachTask(func(task *db.Task) error {
+		data, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// importHandler обрабатывает POST-запросы к /api/import?format=csv|json&mode=append|replace|upsert
+// Принимает файл в формате /api/export, проверяет каждую задачу так же,
+// как при добавлении через POST /api/task, и импортирует все задачи в одной транзакции
+// Если хотя бы одна строка не прошла проверку, ничего не импортируется
+func (h *Handler) importHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это POST-запрос
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	format, err := exportFormat(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = db.ImportAppend
+	}
+	if mode != db.ImportAppend && mode != db.ImportReplace && mode != db.ImportUpsert {
+		writeError(w, db.Invalid("mode", "Параметр mode должен быть append, replace или upsert"))
+		return
+	}
+
+	file, err := importFile(w, r)
+	if err != nil {
+		writeError(w, db.Invalid("file", "Не удалось прочитать файл: %v", err))
+		return
+	}
+
+	var tasks []*db.Task
+	if format == formatCSV {
+		tasks, err = readCSV(file)
+	} else {
+		tasks, err = readJSON(file)
+	}
+	if err != nil {
+		writeError(w, db.Invalid("file", "%v", err))
+		return
+	}
+
+	now, err := h.requestNow(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем все строки, чтобы сообщить обо всех ошибках сразу
+	resp := ImportResp{Mode: mode, Rows: make([]ImportRow, len(tasks))}
+	for i, task := range tasks {
+		resp.Rows[i] = ImportRow{Row: i + 1, ID: task.ID}
+		if err := h.checkImportTask(task, now); err != nil {
+			resp.Rows[i].Error = err.Error()
+			resp.Errors++
+		}
+	}
+	if resp.Errors > 0 {
+		resp.Code = CodeValidation
+		resp.Error = fmt.Sprintf("строк с ошибками: %d, задачи не импортированы", resp.Errors)
+		writeJson(w, resp, http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.store.ImportTasks(tasks, mode)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i, id := range ids {
+		resp.Rows[i].ID = strconv.FormatInt(id, 10)
+	}
+	resp.Imported = len(ids)
+
+	writeJson(w, resp, http.StatusOK)
+}
+
+// checkImportTask проверяет импортируемую задачу и корректирует её дату
+func (h *Handler) checkImportTask(task *db.Task, now time.Time) error {
+	if task.ID != "" {
+		if id, err := strconv.ParseInt(task.ID, 10, 64); err != nil || id < 1 {
+			return db.Invalid("id", "некорректный идентификатор задачи: %s", task.ID)
+		}
+	}
+	if task.Title == "" {
+		return db.Invalid("title", "Не указан заголовок задачи")
+	}
+	if err := h.checkDate(task, now); err != nil {
+		return err
+	}
+	if err := checkTime(task); err != nil {
+		return err
+	}
+	if err := checkTags(task); err != nil {
+		return err
+	}
+	if err := checkCreated(task, now); err != nil {
+		return err
+	}
+	return checkPriority(task)
+}
+
+// checkCreated проверяет момент создания импортируемой задачи и приводит его к UTC
+// Если в файле момента создания нет, задача считается созданной сейчас
+func checkCreated(task *db.Task, now time.Time) error {
+	if task.Created == "" {
+		task.Created = now.UTC().Format(db.DoneAtFormat)
+		return nil
+	}
+	created, err := time.Parse(time.RFC3339, task.Created)
+	if err != nil {
+		return db.Invalid("created", "некорректный момент создания: %s", task.Created)
+	}
+	task.Created = created.UTC().Format(db.DoneAtFormat)
+	return nil
+}
+
+// readCSV читает задачи из CSV; первая строка — заголовок с именами колонок
+// Колонка title обязательна, неизвестные колонки игнорируются
+func readCSV(r io.Reader) ([]*db.Task, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка CSV: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("в заголовке CSV нет колонки title")
+	}
+
+	field := func(rec []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	var tasks []*db.Task
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CSV: %w", err)
+		}
+		task := &db.Task{
+			ID:      field(rec, "id"),
+			Date:    field(rec, "date"),
+			Title:   field(rec, "title"),
+			Comment: field(rec, "comment"),
+			Repeat:  field(rec, "repeat"),
+			Time:    field(rec, "time"),
+			Created: field(rec, "created"),
+		}
+		if v := field(rec, "duration"); v != "" {
+			if task.Duration, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("задача %d: некорректная длительность: %s", len(tasks)+1, v)
+			}
+		}
+		if v := strings.TrimSpace(field(rec, "tags")); v != "" {
+			task.Tags = strings.Split(v, db.TagSeparator)
+		}
+		if v := field(rec, "remaining"); v != "" {
+			if task.Remaining, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("задача %d: некорректное число оставшихся повторений: %s", len(tasks)+1, v)
+			}
+		}
+		if v := field(rec, "priority"); v != "" {
+			if task.Priority, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("задача %d: некорректный приоритет: %s", len(tasks)+1, v)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// readJSON читает задачи из JSON вида {"tasks": [...]} или из массива задач
+func readJSON(r io.Reader) ([]*db.Task, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения JSON: %w", err)
+	}
+
+	var list []*db.Task
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	var resp TasksResp
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("ошибка десериализации JSON")
+	}
+	return resp.Tasks, nil
+}
@@ -7,27 +7,32 @@ import (
 )
 
 // getTaskHandler обрабатывает GET-запросы для получения задачи по ID
-func getTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это GET-запрос
 	if r.Method != http.MethodGet {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
 	// Получаем параметр id из URL
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("id", "Не указан идентификатор"))
 		return
 	}
 
 	// Получаем задачу из базы данных
-	task, err := db.GetTask(id)
+	task, err := h.store.GetTask(id)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+		writeError(w, err)
 		return
 	}
 
+	describeRepeat(requestLang(r), task)
+
+	// Версия задачи нужна клиенту для If-Match при изменении
+	setTaskETag(w, task)
+
 	// Возвращаем задачу в JSON формате
 	writeJson(w, task, http.StatusOK)
 }
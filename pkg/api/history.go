@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// CompletionsResp представляет ответ API со списком выполнений задач
+type CompletionsResp struct {
+	Completions []*db.Completion `json:"completions"`
+}
+
+// taskHistoryHandler обрабатывает GET-запросы к /api/task/history
+// Возвращает историю выполнения задачи с указанным id
+func (h *Handler) taskHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	// Получаем параметр id из URL
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, db.Invalid("id", "Не указан идентификатор"))
+		return
+	}
+
+	// Получаем историю из базы данных
+	list, err := h.store.TaskHistory(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, CompletionsResp{Completions: list}, http.StatusOK)
+}
+
+// completionsHandler обрабатывает GET-запросы к /api/completions
+// Принимает параметры:
+//   - from: первый день периода в формате 20060102 (опционально)
+//   - to: последний день периода в формате 20060102 включительно (опционально)
+func (h *Handler) completionsHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	from, err := parseDayParam(r, "from")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	to, err := parseDayParam(r, "to")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// Последний день включаем в период целиком
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	// Получаем выполнения за период из базы данных
+	list, err := h.store.Completions(from, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, CompletionsResp{Completions: list}, http.StatusOK)
+}
+
+// parseDayParam разбирает параметр запроса с датой в формате 20060102
+// Возвращает начало дня в часовом поясе запроса или нулевое время, если параметр не указан
+func parseDayParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.ParseInLocation(DateFormat, v, loc)
+	if err != nil {
+		return time.Time{}, db.Invalid(name, "параметр %s должен быть датой в формате 20060102", name)
+	}
+	return t, nil
+}
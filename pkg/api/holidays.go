@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"final_project/pkg/db"
+	"final_project/pkg/ical"
+	"final_project/pkg/nextdate"
+)
+
+// holidayDateFormats — допустимые форматы даты праздника в CSV
+var holidayDateFormats = []string{DateFormat, "02.01.2006", "2006-01-02"}
+
+// HolidaysResp представляет ответ API со списком праздников
+type HolidaysResp struct {
+	Holidays []*db.Holiday `json:"holidays"`
+}
+
+// workCalendar возвращает календарь рабочих дней с учётом праздников из БД
+func (h *Handler) workCalendar() (nextdate.Calendar, error) {
+	dates, err := h.store.HolidayDates()
+	if err != nil {
+		return nil, err
+	}
+	return nextdate.Holidays(dates), nil
+}
+
+// holidaysHandler обрабатывает запросы к /api/holidays
+// GET возвращает список праздников, POST загружает праздники из CSV или iCalendar
+func (h *Handler) holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list, err := h.store.Holidays()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJson(w, HolidaysResp{Holidays: list}, http.StatusOK)
+	case http.MethodPost:
+		h.loadHolidaysHandler(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+// loadHolidaysHandler загружает праздники из файла
+// Параметры: format=csv|ics (по умолчанию csv), mode=append|replace (по умолчанию append)
+// В режиме replace прежний список праздников удаляется. Файл с ошибкой не загружается целиком
+func (h *Handler) loadHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ics" {
+		writeError(w, db.Invalid("format", "Параметр format должен быть csv или ics"))
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "append"
+	}
+	if mode != "append" && mode != "replace" {
+		writeError(w, db.Invalid("mode", "Параметр mode должен быть append или replace"))
+		return
+	}
+
+	file, err := importFile(w, r)
+	if err != nil {
+		writeError(w, db.Invalid("file", "Не удалось прочитать файл: %v", err))
+		return
+	}
+
+	// Даты событий в UTC переводятся в часовой пояс пользователя
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var list []*db.Holiday
+	if format == "ics" {
+		list, err = readHolidaysICS(file, loc)
+	} else {
+		list, err = readHolidaysCSV(file)
+	}
+	if err != nil {
+		writeError(w, db.Invalid("file", "%v", err))
+		return
+	}
+
+	if err := h.store.SaveHolidays(list, mode == "replace"); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, map[string]int{"loaded": len(list)}, http.StatusOK)
+}
+
+// readHolidaysCSV читает праздники из CSV с колонками date и name
+// Дата принимается в формате 20060102, 02.01.2006 или 2006-01-02
+func readHolidaysCSV(r io.Reader) ([]*db.Holiday, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения заголовка CSV: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	dateCol, ok := columns["date"]
+	if !ok {
+		return nil, fmt.Errorf("в заголовке CSV нет колонки date")
+	}
+	nameCol, hasName := columns["name"]
+
+	var list []*db.Holiday
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения CSV: %w", err)
+		}
+		if dateCol >= len(rec) {
+			return nil, fmt.Errorf("строка %d: не указана дата", line)
+		}
+		date, err := parseHolidayDate(rec[dateCol])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", line, err)
+		}
+		h := &db.Holiday{Date: date}
+		if hasName && nameCol < len(rec) {
+			h.Name = strings.TrimSpace(rec[nameCol])
+		}
+		list = append(list, h)
+	}
+	return list, nil
+}
+
+// parseHolidayDate приводит дату праздника к формату 20060102
+func parseHolidayDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range holidayDateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("некорректная дата праздника: %q", s)
+}
+
+// readHolidaysICS читает праздники из событий календаря: DTSTART → date, SUMMARY → name
+// Повторяющиеся события не разворачиваются, учитывается только дата начала
+// Даты в UTC переводятся в часовой пояс loc
+func readHolidaysICS(r io.Reader, loc *time.Location) ([]*db.Holiday, error) {
+	components, err := ical.Parse(r, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*db.Holiday, 0, len(components))
+	for _, c := range components {
+		if c.Err != nil {
+			return nil, fmt.Errorf("строка %d: %w", c.Line, c.Err)
+		}
+		list = append(list, &db.Holiday{Date: c.Date, Name: c.Summary})
+	}
+	return list, nil
+}
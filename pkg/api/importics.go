@@ -0,0 +1,159 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"final_project/pkg/db"
+	"final_project/pkg/ical"
+)
+
+// maxImportSize — максимальный размер загружаемого файла импорта
+const maxImportSize = 10 << 20
+
+// Статусы события в ответе импорта
+const (
+	importCreated     = "created"      // задача создана
+	importWouldCreate = "would_create" // задача была бы создана (dry_run)
+	importError       = "error"        // событие пропущено из-за ошибки
+)
+
+// ImportEvent описывает результат импорта одного события
+type ImportEvent struct {
+	UID    string   `json:"uid,omitempty"`
+	Line   int      `json:"line"`
+	Status string   `json:"status"`
+	Error  string   `json:"error,omitempty"`
+	Task   *db.Task `json:"task,omitempty"`
+}
+
+// ImportICSResp представляет ответ API импорта календаря
+type ImportICSResp struct {
+	DryRun  bool          `json:"dry_run"`
+	Created int           `json:"created"`
+	Errors  int           `json:"errors"`
+	Events  []ImportEvent `json:"events"`
+}
+
+// importFile возвращает содержимое загруженного файла
+// Файл принимается в поле file формы multipart/form-data или как тело запроса целиком
+func importFile(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// importICSHandler обрабатывает POST-запросы к /api/import/ics
+// Создаёт задачи из событий календаря: SUMMARY → title, DESCRIPTION → comment,
+// DTSTART → date, RRULE → repeat. С параметром dry_run=true только сообщает,
+// какие задачи были бы созданы. Все задачи добавляются в одной транзакции
+func (h *Handler) importICSHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это POST-запрос
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, db.Invalid("dry_run", "Параметр dry_run должен быть true или false"))
+			return
+		}
+	}
+
+	file, err := importFile(w, r)
+	if err != nil {
+		writeError(w, db.Invalid("file", "Не удалось прочитать файл: %v", err))
+		return
+	}
+
+	// Даты событий в UTC и «сегодня» определяются в часовом поясе пользователя
+	now, err := h.requestNow(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	components, err := ical.Parse(file, now.Location())
+	if err != nil {
+		writeError(w, db.Invalid("file", "%v", err))
+		return
+	}
+
+	resp := ImportICSResp{DryRun: dryRun, Events: make([]ImportEvent, 0, len(components))}
+	var tasks []*db.Task
+	var created []int // индексы событий в resp.Events, для которых создаются задачи
+
+	for _, c := range components {
+		ev := ImportEvent{UID: c.UID, Line: c.Line}
+		task, err := h.componentTask(c, now)
+		if err != nil {
+			ev.Status = importError
+			ev.Error = err.Error()
+			resp.Errors++
+		} else {
+			ev.Status = importWouldCreate
+			ev.Task = task
+			tasks = append(tasks, task)
+			created = append(created, len(resp.Events))
+		}
+		resp.Events = append(resp.Events, ev)
+	}
+
+	if !dryRun && len(tasks) > 0 {
+		ids, err := h.store.AddTasks(tasks)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for i, idx := range created {
+			resp.Events[idx].Status = importCreated
+			resp.Events[idx].Task.ID = strconv.FormatInt(ids[i], 10)
+		}
+		resp.Created = len(ids)
+	}
+
+	writeJson(w, resp, http.StatusOK)
+}
+
+// componentTask формирует задачу из события календаря и проверяет её
+// так же, как при добавлении задачи через POST /api/task
+func (h *Handler) componentTask(c ical.Component, now time.Time) (*db.Task, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+
+	repeat, err := ical.Repeat(c.RRule, c.Date, now.Location())
+	if err != nil {
+		return nil, err
+	}
+
+	task := &db.Task{
+		Date:    c.Date,
+		Title:   c.Summary,
+		Comment: c.Description,
+		Repeat:  repeat,
+		Created: now.UTC().Format(db.DoneAtFormat),
+	}
+	if task.Title == "" {
+		return nil, fmt.Errorf("Не указан заголовок задачи")
+	}
+	if err := h.checkDate(task, now); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
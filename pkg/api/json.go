@@ -2,10 +2,32 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strings"
+
+	"final_project/pkg/db"
+)
+
+// Коды ошибок в ответах API
+const (
+	CodeValidation       = "validation"          // некорректные параметры или тело запроса
+	CodeNotFound         = "not_found"           // задача или другой объект не найден
+	CodeConflict         = "conflict"            // запрос противоречит текущему состоянию данных
+	CodePrecondition     = "precondition_failed" // задача изменилась после чтения (If-Match)
+	CodeUnauthorized     = "unauthorized"        // требуется аутентификация
+	CodeForbidden        = "forbidden"           // доступ запрещён настройками сервера
+	CodeMethodNotAllowed = "method_not_allowed"  // HTTP-метод не поддерживается
+	CodeInternal         = "internal"            // внутренняя ошибка сервера
 )
 
+// ErrorResp — единый формат ответа API с ошибкой
+// Текст ошибки передаётся в поле error, как и раньше, поэтому существующие клиенты продолжают работать
+type ErrorResp struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+}
+
 // writeJson сериализует данные в JSON и отправляет HTTP-ответ с заданным статусом
 func writeJson(w http.ResponseWriter, data any, status int) error {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -13,14 +35,56 @@ func writeJson(w http.ResponseWriter, data any, status int) error {
 	return json.NewEncoder(w).Encode(data)
 }
 
-// errorStatus сопоставляет ошибку с HTTP статусом
-// если в тексте ошибки есть "не найдена" — возвращаем 404, иначе 500
+// writeError отправляет ошибку в формате ErrorResp
+// Статус и код определяются категорией ошибки (см. errorStatus), поле — из db.Error
+func writeError(w http.ResponseWriter, err error) {
+	resp := ErrorResp{Code: errorCode(err), Error: err.Error()}
+	var e *db.Error
+	if errors.As(err, &e) {
+		resp.Field = e.Field
+	}
+	writeJson(w, resp, errorStatus(err))
+}
+
+// writeErrorCode отправляет ошибку, не относящуюся к категориям db, с явными статусом и кодом
+func writeErrorCode(w http.ResponseWriter, status int, code, msg string) {
+	writeJson(w, ErrorResp{Code: code, Error: msg}, status)
+}
+
+// methodNotAllowed отвечает, что HTTP-метод не поддерживается
+func methodNotAllowed(w http.ResponseWriter) {
+	writeErrorCode(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Метод не поддерживается")
+}
+
+// errorStatus сопоставляет ошибку с HTTP статусом по её категории
+// ошибки без категории считаются внутренними (500)
 func errorStatus(err error) int {
-	if err == nil {
+	switch {
+	case err == nil:
 		return http.StatusOK
-	}
-	if strings.Contains(err.Error(), "не найдена") {
+	case errors.Is(err, db.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotFound):
 		return http.StatusNotFound
+	case errors.Is(err, db.ErrVersionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
 	}
 	return http.StatusInternalServerError
 }
+
+// errorCode возвращает код ErrorResp для категории ошибки
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, db.ErrValidation):
+		return CodeValidation
+	case errors.Is(err, db.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, db.ErrVersionMismatch):
+		return CodePrecondition
+	case errors.Is(err, db.ErrConflict):
+		return CodeConflict
+	}
+	return CodeInternal
+}
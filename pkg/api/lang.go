@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"final_project/pkg/db"
+	"final_project/pkg/nextdate"
+)
+
+// requestLang выбирает язык ответа по заголовку Accept-Language
+// Поддерживаются русский и английский; если подходящего языка нет, используется русский
+func requestLang(r *http.Request) string {
+	lang, best := nextdate.LangRu, 0.0
+	for _, item := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		// Из тега вида en-US нужен только основной язык
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if (primary == nextdate.LangRu || primary == nextdate.LangEn) && q > best {
+			lang, best = primary, q
+		}
+	}
+	return lang
+}
+
+// describeRepeat заполняет у задач описание правила повторения на языке lang
+func describeRepeat(lang string, tasks ...*db.Task) {
+	for _, task := range tasks {
+		task.RepeatText = task.RepeatRule.Describe(lang)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"final_project/pkg/db"
 	"final_project/pkg/nextdate"
 )
 
@@ -13,55 +14,58 @@ const DateFormat = "20060102"
 
 // NextDateHandler обрабатывает GET-запросы к /api/nextdate
 // Принимает параметры:
-//   - now: текущая дата в формате 20060102 (опционально, если не указана - используется текущая дата)
+//   - now: текущая дата в формате 20060102 или момент времени в формате RFC 3339
+//     (опционально, если не указана - используется текущая дата в часовом поясе запроса)
 //   - date: исходная дата в формате 20060102
 //   - repeat: правило повторения
 //
-// Возвращает следующую дату в формате 20060102 или текст ошибки
-func NextDateHandler(w http.ResponseWriter, r *http.Request) {
+// Возвращает следующую дату в формате 20060102 текстом или ошибку в формате ErrorResp
+func (h *Handler) NextDateHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это GET-запрос
 	if r.Method != http.MethodGet {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
 	// Получаем параметры из URL
-	nowParam := r.FormValue("now")
 	dateParam := r.FormValue("date")
 	repeatParam := r.FormValue("repeat")
 
 	// Проверяем обязательные параметры
 	if dateParam == "" {
-		http.Error(w, "Параметр date обязателен", http.StatusBadRequest)
+		writeError(w, db.Invalid("date", "Параметр date обязателен"))
+		return
+	}
+	if _, err := time.Parse(DateFormat, dateParam); err != nil {
+		writeError(w, db.Invalid("date", "Параметр date должен быть в формате 20060102"))
+		return
+	}
+
+	// Определяем время now: без параметра — текущее время в часовом поясе запроса
+	now, err := h.parseNowParam(r)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Определяем время now
-	var now time.Time
-	if nowParam == "" {
-		// Если параметр now не указан, используем текущую дату
-		now = time.Now()
-	} else {
-		// Парсим переданную дату now
-		parsedNow, err := time.Parse(DateFormat, nowParam)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Некорректный формат параметра now: %s", nowParam), http.StatusBadRequest)
-			return
-		}
-		now = parsedNow
+	// Рабочие дни определяем с учётом загруженных праздников
+	cal, err := h.workCalendar()
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
-	// Вызываем функцию NextDate из пакета nextdate
-	nextDate, err := nextdate.NextDate(now, dateParam, repeatParam)
+	// Вызываем функцию NextDateCal из пакета nextdate
+	nextDate, err := nextdate.NextDateCal(now, dateParam, repeatParam, cal)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, db.Invalid("repeat", "%v", err))
 		return
 	}
 
 	// Возвращаем результат в формате 20060102
 	// Если nextDate пустой, возвращаем ошибку
 	if nextDate == "" {
-		http.Error(w, "Некорректное правило повторения", http.StatusBadRequest)
+		writeError(w, db.Invalid("repeat", "Некорректное правило повторения"))
 		return
 	}
 
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"final_project/pkg/db"
+	"final_project/pkg/nextdate"
+)
+
+// Ограничения количества дат в предпросмотре повторений
+const (
+	defaultOccurrences = 10
+	maxOccurrences     = 100
+)
+
+// OccurrencesResp представляет ответ API предпросмотра повторений
+type OccurrencesResp struct {
+	Dates []string `json:"dates"`
+}
+
+// occurrencesHandler обрабатывает GET-запросы к /api/occurrences
+// Принимает параметры:
+//   - date: дата задачи в формате 20060102 (опционально, по умолчанию сегодня)
+//   - repeat: правило повторения
+//   - count: сколько дат вернуть (по умолчанию 10, не больше 100)
+//   - until: последняя дата в формате 20060102 включительно (опционально)
+//   - now: текущая дата в формате 20060102 или момент времени RFC 3339 (опционально)
+//
+// Первая дата — та, которую получит задача при сохранении, следующие вычисляются
+// последовательно через NextDate. Условия until и count из правила тоже учитываются
+func (h *Handler) occurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+
+	count := defaultOccurrences
+	if v := query.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, db.Invalid("count", "Параметр count должен быть положительным числом"))
+			return
+		}
+		count = min(n, maxOccurrences)
+	}
+
+	until := query.Get("until")
+	if until != "" {
+		if _, err := time.Parse(DateFormat, until); err != nil {
+			writeError(w, db.Invalid("until", "Параметр until должен быть в формате 20060102"))
+			return
+		}
+	}
+
+	now, err := h.parseNowParam(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Первую дату определяем так же, как при добавлении задачи
+	task := &db.Task{Date: query.Get("date"), Repeat: query.Get("repeat")}
+	if err := h.checkDate(task, now); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	cal, err := h.workCalendar()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	dates, err := occurrences(task, cal, count, until)
+	if err != nil {
+		writeError(w, db.Invalid("repeat", "%v", err))
+		return
+	}
+
+	writeJson(w, OccurrencesResp{Dates: dates}, http.StatusOK)
+}
+
+// occurrences возвращает до count дат задачи, начиная с её текущей даты
+// Даты позже until (если он указан) не включаются
+func occurrences(task *db.Task, cal nextdate.Calendar, count int, until string) ([]string, error) {
+	dates := []string{}
+	date, anchor, remaining := task.Date, task.Anchor, task.Remaining
+
+	for len(dates) < count && (until == "" || date <= until) {
+		dates = append(dates, date)
+
+		// Разовая задача или последнее из count повторений
+		if task.Repeat == "" || remaining == 1 {
+			break
+		}
+
+		t, err := time.Parse(DateFormat, date)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная дата: %s", date)
+		}
+		// Следующую дату отсчитываем от даты до переноса, чтобы перенос не накапливался
+		next, nextAnchor, err := task.RepeatRule.NextShifted(t, date, anchor, cal)
+		if errors.Is(err, nextdate.ErrEnded) || (err == nil && next == "") {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		date, anchor = next, nextAnchor
+		if remaining > 0 {
+			remaining--
+		}
+	}
+	return dates, nil
+}
@@ -0,0 +1,14 @@
+package api
+
+import (
+	"final_project/pkg/db"
+)
+
+// checkPriority проверяет, что приоритет задачи лежит в допустимых границах
+func checkPriority(task *db.Task) error {
+	if task.Priority < db.PriorityNone || task.Priority > db.PriorityMax {
+		return db.Invalid("priority", "приоритет должен быть от %d до %d, получено: %d",
+			db.PriorityNone, db.PriorityMax, task.Priority)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// Store — хранилище, с которым работают обработчики API
+// Реализуется *db.Storage; в тестах его можно заменить хранилищем в памяти
+type Store interface {
+	// Задачи
+	AddTask(task *db.Task) (int64, error)
+	Tasks(filter db.TaskFilter) (*db.TasksPage, error)
+	GetTask(id string) (*db.Task, error)
+	UpdateTask(task *db.Task, keep db.Fields) error
+	DeleteTask(id string, version int, undo *db.UndoRecord) error
+	CompleteTask(id string, version int, now time.Time, undo *db.UndoRecord) (*db.Done, error)
+
+	// Массовые операции: импорт и экспорт
+	AddTasks(tasks []*db.Task) ([]int64, error)
+	ImportTasks(tasks []*db.Task, mode string) ([]int64, error)
+	EachTask(fn func(*db.Task) error) error
+
+	// Метки
+	Tags() ([]*db.Tag, error)
+
+	// История выполнения
+	TaskHistory(taskID string) ([]*db.Completion, error)
+	Completions(from, to time.Time) ([]*db.Completion, error)
+
+	// Отмена выполнения и удаления
+	Undo(token string, now time.Time) (*db.Task, error)
+
+	// Праздники
+	Holidays() ([]*db.Holiday, error)
+	HolidayDates() (map[string]bool, error)
+	SaveHolidays(list []*db.Holiday, replace bool) error
+}
+
+// Проверяем на этапе компиляции, что хранилище SQLite реализует Store
+var _ Store = (*db.Storage)(nil)
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"final_project/pkg/db"
+)
+
+// maxTagLength — максимальная длина метки в символах
+const maxTagLength = 64
+
+// TagsResp представляет ответ API со списком меток
+type TagsResp struct {
+	Tags []*db.Tag `json:"tags"`
+}
+
+// tagsHandler обрабатывает GET-запросы к /api/tags
+// Возвращает метки, которыми отмечена хотя бы одна задача, с количеством задач
+func (h *Handler) tagsHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это GET-запрос
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	list, err := h.store.Tags()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJson(w, TagsResp{Tags: list}, http.StatusOK)
+}
+
+// normalizeTags приводит метки к нижнему регистру без пробелов по краям,
+// убирает повторы и сортирует; field — поле или параметр запроса для текста ошибки
+func normalizeTags(tags []string, field string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var list []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case tag == "":
+			return nil, db.Invalid(field, "метка не может быть пустой")
+		case strings.Contains(tag, db.TagSeparator):
+			return nil, db.Invalid(field, "метка %q не может содержать %q", tag, db.TagSeparator)
+		case utf8.RuneCountInString(tag) > maxTagLength:
+			return nil, db.Invalid(field, "метка %q длиннее %d символов", tag, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			list = append(list, tag)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// checkTags проверяет метки задачи и приводит их к виду, в котором они хранятся
+func checkTags(task *db.Task) error {
+	tags, err := normalizeTags(task.Tags, "tags")
+	if err != nil {
+		return err
+	}
+	task.Tags = tags
+	return nil
+}
+
+// parseTagFilter заполняет фильтр по меткам из параметров tag и tag_mode
+// Параметр tag можно повторять или перечислять метки в нём через запятую
+func parseTagFilter(r *http.Request, filter *db.TaskFilter) error {
+	q := r.URL.Query()
+
+	var tags []string
+	for _, v := range q["tag"] {
+		tags = append(tags, strings.Split(v, db.TagSeparator)...)
+	}
+	tags, err := normalizeTags(tags, "tag")
+	if err != nil {
+		return err
+	}
+	filter.Tags = tags
+
+	switch v := q.Get("tag_mode"); v {
+	case "", db.TagModeAnd, db.TagModeOr:
+		filter.TagMode = v
+	default:
+		return db.Invalid("tag_mode", "параметр tag_mode должен быть and или or")
+	}
+	return nil
+}
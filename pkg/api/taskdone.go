@@ -2,58 +2,56 @@ package api
 
 import (
 	"net/http"
-	"time"
 
 	"final_project/pkg/db"
-	"final_project/pkg/nextdate"
 )
 
 // taskDoneHandler обрабатывает POST-запросы для отметки задачи как выполненной
-func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это POST-запрос
 	if r.Method != http.MethodPost {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
 	// Получаем параметр id из URL
 	id := r.URL.Query().Get("id")
 	if id == "" {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("id", "Не указан идентификатор"))
 		return
 	}
 
-	// Получаем задачу из базы данных
-	task, err := db.GetTask(id)
+	// «Сегодня» определяется в часовом поясе пользователя
+	now, err := h.requestNow(r)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+		writeError(w, err)
 		return
 	}
 
-	// Если правило повторения отсутствует, удаляем задачу
-	if task.Repeat == "" {
-		err = db.DeleteTask(id)
-		if err != nil {
-			writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
-			return
-		}
-	} else {
-		// Если задача периодическая, вычисляем следующую дату
-		now := time.Now()
-		nextDate, err := nextdate.NextDate(now, task.Date, task.Repeat)
-		if err != nil {
-			// ошибка в вычислении следующей даты — это некорректные входные данные
-			writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
-			return
-		}
-
-		// Обновляем дату задачи
-		err = db.UpdateDate(nextDate, id)
-		if err != nil {
-			writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
-			return
-		}
+	// Задача могла измениться после того, как клиент её прочитал
+	version, err := h.ifMatchVersion(r, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	undo, err := newUndo(now)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Чтение задачи, перенос на следующую дату (или удаление), запись в историю и снимок для отмены
+	// выполняются в одной транзакции, поэтому одновременные запросы не теряют повторений
+	done, err := h.store.CompleteTask(id, version, now, undo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if done.Version != 0 {
+		w.Header().Set("ETag", taskETag(done.Version))
 	}
+	setUndoHeader(w, undo)
 
 	// Возвращаем пустой JSON при успешном выполнении
 	writeJson(w, map[string]interface{}{}, http.StatusOK)
@@ -1,36 +1,237 @@
 package api
 
 import (
+	"encoding/base64"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"final_project/pkg/db"
 )
 
+// Ограничения размера страницы списка задач
+const (
+	defaultTasksLimit = 50  // размер страницы по умолчанию, его ожидает веб-интерфейс
+	maxTasksLimit     = 500 // максимальный размер страницы
+)
+
 // TasksResp представляет ответ API для получения списка задач
 type TasksResp struct {
-	Tasks []*db.Task `json:"tasks"`
+	Tasks      []*db.Task `json:"tasks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Total      int        `json:"total"`
 }
 
 // tasksHandler обрабатывает GET-запросы для получения списка задач
-func tasksHandler(w http.ResponseWriter, r *http.Request) {
+// Принимает параметры:
+//   - search: строка поиска (опционально)
+//   - order: порядок результатов поиска, rank или date
+//   - sort: сортировка списка, date, priority, title или created
+//   - limit: размер страницы (по умолчанию 50)
+//   - cursor: позиция страницы из next_cursor предыдущего ответа
+//   - from, to: диапазон дат в формате 20060102 включительно
+//   - repeat: yes — только периодические задачи, no — только разовые
+//   - overdue: true — только просроченные задачи
+//   - tag: метки через запятую или повторением параметра
+//   - tag_mode: and — задачи со всеми метками (по умолчанию), or — хотя бы с одной
+func (h *Handler) tasksHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это GET-запрос
 	if r.Method != http.MethodGet {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
-	// Получаем параметр поиска из URL
-	search := r.URL.Query().Get("search")
+	// Разбираем параметры запроса
+	filter, err := h.parseTaskFilter(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
-	// Получаем список задач из базы данных (максимум 50)
-	tasks, err := db.Tasks(50, search)
+	// Получаем страницу задач из базы данных
+	page, err := h.store.Tasks(filter)
 	if err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+		writeError(w, err)
 		return
 	}
 
+	describeRepeat(requestLang(r), page.Tasks...)
+
+	resp := TasksResp{
+		Tasks: page.Tasks,
+		Total: page.Total,
+	}
+	if page.Next != nil {
+		resp.NextCursor = encodeCursor(*page.Next)
+	}
+
 	// Возвращаем список задач в JSON формате
-	writeJson(w, TasksResp{
-		Tasks: tasks,
-	}, http.StatusOK)
+	writeJson(w, resp, http.StatusOK)
+}
+
+// parseTaskFilter формирует фильтр списка задач из параметров запроса
+func (h *Handler) parseTaskFilter(r *http.Request) (db.TaskFilter, error) {
+	q := r.URL.Query()
+	filter := db.TaskFilter{
+		Limit:  defaultTasksLimit,
+		Search: q.Get("search"),
+		Order:  q.Get("order"),
+	}
+
+	// Порядок результатов поиска: по релевантности (по умолчанию) или по дате
+	if filter.Order == "" {
+		filter.Order = db.OrderRank
+	}
+	if filter.Order != db.OrderRank && filter.Order != db.OrderDate {
+		return filter, db.Invalid("order", "параметр order должен быть rank или date")
+	}
+
+	// Сортировка списка; если задана, заменяет порядок результатов поиска
+	switch v := q.Get("sort"); v {
+	case "", db.SortDate, db.SortPriority, db.SortTitle, db.SortCreated:
+		filter.Sort = v
+	default:
+		return filter, db.Invalid("sort", "параметр sort должен быть date, priority, title или created")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTasksLimit {
+			return filter, db.Invalid("limit", "параметр limit должен быть числом от 1 до %d", maxTasksLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := decodeCursor(v)
+		if err != nil {
+			return filter, err
+		}
+		// Позиция в списке с другой сортировкой не имеет смысла, а смещение подходит только
+		// для поиска по релевантности: иначе курсор был бы молча пропущен и страница повторилась
+		sort := filter.Sort
+		if sort == "" {
+			sort = db.SortDate
+		}
+		offset := cursor.Date == ""
+		switch {
+		case filter.ByRank() && !offset:
+			return filter, db.Invalid("cursor", "курсор получен для списка, а не для поиска по релевантности")
+		case !filter.ByRank() && offset:
+			return filter, db.Invalid("cursor", "курсор получен для поиска по релевантности")
+		case !offset && cursor.Sort != sort:
+			return filter, db.Invalid("cursor", "курсор получен для другой сортировки")
+		}
+		filter.Cursor = cursor
+	}
+
+	for _, p := range []struct {
+		name string
+		dst  *string
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(DateFormat, v); err != nil {
+			return filter, db.Invalid(p.name, "параметр %s должен быть датой в формате 20060102", p.name)
+		}
+		*p.dst = v
+	}
+
+	switch v := q.Get("repeat"); v {
+	case db.RepeatAny, db.RepeatYes, db.RepeatNo:
+		filter.Repeat = v
+	default:
+		return filter, db.Invalid("repeat", "параметр repeat должен быть yes или no")
+	}
+
+	if v := q.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, db.Invalid("overdue", "параметр overdue должен быть true или false")
+		}
+		if overdue {
+			now, err := h.requestNow(r)
+			if err != nil {
+				return filter, err
+			}
+			filter.Overdue = now.Format(DateFormat)
+		}
+	}
+
+	if err := parseTagFilter(r, &filter); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// encodeCursor кодирует позицию страницы в непрозрачную строку
+func encodeCursor(c db.Cursor) string {
+	var raw string
+	switch {
+	case c.Date == "":
+		raw = "o:" + strconv.Itoa(c.Offset)
+	case c.Sort == db.SortDate:
+		// Двоеточие во времени убираем, так как оно разделяет части курсора
+		raw = "d:" + c.Date + ":" + strings.ReplaceAll(c.Time, ":", "") + ":" + strconv.FormatInt(c.ID, 10)
+	default:
+		// Ключ сортировки (например, заголовок) может содержать двоеточия, поэтому он последний
+		raw = "k:" + c.Sort + ":" + c.Date + ":" + strings.ReplaceAll(c.Time, ":", "") + ":" +
+			strconv.FormatInt(c.ID, 10) + ":" + c.Key
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor восстанавливает позицию страницы из строки encodeCursor
+func decodeCursor(s string) (db.Cursor, error) {
+	var c db.Cursor
+	errCursor := db.Invalid("cursor", "некорректное значение параметра cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 6)
+	switch {
+	case len(parts) == 4 && parts[0] == "d":
+		c.Sort = db.SortDate
+	case len(parts) == 6 && parts[0] == "k":
+		switch parts[1] {
+		case db.SortPriority, db.SortTitle, db.SortCreated:
+		default:
+			return c, errCursor
+		}
+		c.Sort, c.Key = parts[1], parts[5]
+		parts = parts[1:5]
+	case len(parts) == 2 && parts[0] == "o":
+		offset, err := strconv.Atoi(parts[1])
+		if err != nil || offset < 0 {
+			return c, errCursor
+		}
+		c.Offset = offset
+		return c, nil
+	default:
+		return c, errCursor
+	}
+
+	// Позиция (дата, время, id) в parts[1:4]
+	if _, err := time.Parse(DateFormat, parts[1]); err != nil {
+		return c, errCursor
+	}
+	if parts[2] != "" {
+		if _, err := time.Parse("1504", parts[2]); err != nil || len(parts[2]) != 4 {
+			return c, errCursor
+		}
+		c.Time = parts[2][:2] + ":" + parts[2][2:]
+	}
+	id, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return c, errCursor
+	}
+	c.Date, c.ID = parts[1], id
+	return c, nil
 }
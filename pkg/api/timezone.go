@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// Настройки часового пояса планировщика
+const (
+	envTimezoneKey = "TODO_TZ"    // часовой пояс по умолчанию, например Europe/Moscow
+	timezoneHeader = "X-Timezone" // часовой пояс клиента для отдельного запроса
+)
+
+// requestLocation возвращает часовой пояс, в котором определяется «сегодня» для запроса
+// Приоритет: заголовок X-Timezone, затем TODO_TZ, затем местный пояс сервера
+// Неизвестный пояс в заголовке — ошибка запроса; невалидный TODO_TZ, как и другие
+// переменные окружения, заменяется значением по умолчанию
+func requestLocation(r *http.Request) (*time.Location, error) {
+	if name := r.Header.Get(timezoneHeader); name != "" {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, db.Invalid(timezoneHeader, "неизвестный часовой пояс в заголовке %s: %s", timezoneHeader, name)
+		}
+		return loc, nil
+	}
+	return defaultLocation(), nil
+}
+
+// defaultLocation возвращает часовой пояс из TODO_TZ или местный пояс сервера
+func defaultLocation() *time.Location {
+	if name := os.Getenv(envTimezoneKey); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// requestNow возвращает текущее время в часовом поясе запроса
+func (h *Handler) requestNow(r *http.Request) (time.Time, error) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return h.clock.Now().In(loc), nil
+}
+
+// parseNowParam разбирает параметр now: дату 20060102 или момент времени в формате RFC 3339
+// Момент времени переводится в часовой пояс запроса; без параметра возвращается текущее время
+func (h *Handler) parseNowParam(r *http.Request) (time.Time, error) {
+	v := r.FormValue("now")
+	if v == "" {
+		return h.requestNow(r)
+	}
+	if t, err := time.Parse(DateFormat, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, db.Invalid("now", "Некорректный формат параметра now: %s", v)
+	}
+	loc, err := requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
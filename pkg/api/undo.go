@@ -0,0 +1,72 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"time"
+
+	"final_project/pkg/db"
+)
+
+// Константы для отмены операций
+const (
+	envUndoWindowKey  = "TODO_UNDO_WINDOW" // имя переменной окружения со сроком отмены (например, 10m)
+	defaultUndoWindow = 10 * time.Minute   // срок отмены по умолчанию
+	undoHeader        = "X-Undo-Token"     // заголовок ответа с токеном отмены
+)
+
+// undoWindow возвращает срок, в течение которого операцию можно отменить
+// Читает переменную окружения TODO_UNDO_WINDOW, при невалидном значении использует значение по умолчанию
+func undoWindow() time.Duration {
+	if v, ok := os.LookupEnv(envUndoWindowKey); ok {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultUndoWindow
+}
+
+// newUndo создаёт токен отмены; снимок задачи хранилище записывает вместе с самой операцией
+func newUndo(now time.Time) (*db.UndoRecord, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return &db.UndoRecord{Token: hex.EncodeToString(buf), Now: now, Expires: now.Add(undoWindow())}, nil
+}
+
+// setUndoHeader передаёт токен отмены в заголовке ответа
+// Тело ответа не меняется, поэтому клиенты, не знающие об отмене, работают как прежде
+func setUndoHeader(w http.ResponseWriter, undo *db.UndoRecord) {
+	w.Header().Set(undoHeader, undo.Token)
+}
+
+// undoHandler обрабатывает POST-запросы к /api/task/undo?token=
+// Восстанавливает задачу в состоянии до выполнения или удаления
+func (h *Handler) undoHandler(w http.ResponseWriter, r *http.Request) {
+	// Проверяем, что это POST-запрос
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	// Получаем параметр token из URL
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, db.Invalid("token", "Не указан токен отмены"))
+		return
+	}
+
+	// Восстанавливаем задачу
+	task, err := h.store.Undo(token, h.clock.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Возвращаем восстановленную задачу
+	setTaskETag(w, task)
+	writeJson(w, task, http.StatusOK)
+}
@@ -7,45 +7,125 @@ import (
 	"final_project/pkg/db"
 )
 
+// taskUpdate — тело запроса PUT /api/task
+// Необязательные поля объявлены указателями: nil означает, что поля нет в запросе,
+// и задача сохраняет прежнее значение (веб-интерфейс передаёт не все поля задачи)
+type taskUpdate struct {
+	db.Task
+	Repeat   *string   `json:"repeat"` // без поля repeat правило берётся из repeat_rule
+	Tags     *[]string `json:"tags"`
+	Priority *int      `json:"priority"`
+	Time     *string   `json:"time"`
+	Duration *int      `json:"duration"`
+}
+
+// task возвращает задачу из запроса и набор полей, которые нужно оставить без изменений
+func (u *taskUpdate) task() (db.Task, db.Fields) {
+	task := u.Task
+	var keep db.Fields
+	if u.Tags != nil {
+		task.Tags = *u.Tags
+	} else {
+		keep |= db.FieldTags
+	}
+	if u.Priority != nil {
+		task.Priority = *u.Priority
+	} else {
+		keep |= db.FieldPriority
+	}
+	// Время и длительность заменяются вместе: отсутствующее из них поле считается пустым
+	if u.Time != nil || u.Duration != nil {
+		task.Time, task.Duration = "", 0
+		if u.Time != nil {
+			task.Time = *u.Time
+		}
+		if u.Duration != nil {
+			task.Duration = *u.Duration
+		}
+	} else {
+		keep |= db.FieldTime
+	}
+	return task, keep
+}
+
 // updateTaskHandler обрабатывает PUT-запросы для обновления задачи
-func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
+// Поля, которых нет в запросе (метки, приоритет, время с длительностью), сохраняют прежние значения;
+// пустой массив tags удаляет метки, пустая строка time делает задачу задачей на весь день
+func (h *Handler) updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	// Проверяем, что это PUT-запрос
 	if r.Method != http.MethodPut {
-		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		methodNotAllowed(w)
 		return
 	}
 
-	var task db.Task
-
 	// Десериализуем JSON
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		writeJson(w, map[string]string{"error": "ошибка десериализации JSON"}, http.StatusBadRequest)
+	var update taskUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, db.Invalid("", "ошибка десериализации JSON"))
+		return
+	}
+	task, keep := update.task()
+	if err := checkRepeatRule(&task, update.Repeat); err != nil {
+		writeError(w, err)
 		return
 	}
 
+	// Счётчик оставшихся повторений ведёт сервер: он сохраняется, пока не изменилось число повторений
+	task.Remaining = 0
+
 	// Проверяем обязательное поле title
 	if task.Title == "" {
-		writeJson(w, map[string]string{"error": "Не указан заголовок задачи"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("title", "Не указан заголовок задачи"))
 		return
 	}
 
 	// Проверяем обязательное поле id
 	if task.ID == "" {
-		writeJson(w, map[string]string{"error": "Не указан идентификатор задачи"}, http.StatusBadRequest)
+		writeError(w, db.Invalid("id", "Не указан идентификатор задачи"))
+		return
+	}
+
+	// Проверяем и корректируем дату относительно «сегодня» в часовом поясе пользователя
+	now, err := h.requestNow(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := h.checkDate(&task, now); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем время и длительность
+	if err := checkTime(&task); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем метки
+	if err := checkTags(&task); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// Проверяем приоритет
+	if err := checkPriority(&task); err != nil {
+		writeError(w, err)
 		return
 	}
 
-	// Проверяем и корректируем дату
-	if err := checkDate(&task); err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, http.StatusBadRequest)
+	// Ожидаемую версию задачи клиент передаёт в If-Match; без заголовка задача перезаписывается
+	if task.Version, err = h.ifMatchVersion(r, task.ID); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	// Обновляем задачу в базе данных
-	if err := db.UpdateTask(&task); err != nil {
-		writeJson(w, map[string]string{"error": err.Error()}, errorStatus(err))
+	if err := h.store.UpdateTask(&task, keep); err != nil {
+		writeError(w, err)
 		return
 	}
+	setTaskETag(w, &task)
 
 	// Возвращаем пустой JSON при успешном обновлении
 	writeJson(w, map[string]interface{}{}, http.StatusOK)
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"final_project/pkg/nextdate"
+)
+
+// DoneAtFormat — формат хранения момента выполнения (UTC)
+// Строки этого формата сравниваются лексикографически в порядке времени
+const DoneAtFormat = "2006-01-02T15:04:05Z"
+
+// Completion представляет запись о выполнении задачи
+type Completion struct {
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`     // заголовок задачи на момент выполнения
+	Date     string `json:"date"`      // дата, на которую была запланирована задача
+	DoneAt   string `json:"done_at"`   // момент выполнения в UTC
+	NextDate string `json:"next_date"` // следующая дата или пустая строка для разовой задачи
+}
+
+// Done — результат выполнения задачи
+type Done struct {
+	Task         *Task  // задача в состоянии до выполнения
+	NextDate     string // следующая дата или пустая строка, если задача удалена
+	Version      int    // версия перенесённой задачи или 0, если задача удалена
+	CompletionID int64  // ID записи истории выполнения
+}
+
+// CompleteTask отмечает задачу выполненной в одной транзакции BEGIN IMMEDIATE:
+// читает задачу, вычисляет следующую дату с учётом праздников, переносит задачу
+// (разовая задача и завершённая серия удаляются) и записывает выполнение в историю
+// Одновременные вызовы для одной задачи выполняются по очереди, поэтому каждый
+// переносит задачу ровно на одно повторение от даты, записанной предыдущим
+// Если version больше нуля и не совпадает с версией задачи, возвращается ErrVersionMismatch
+// now — текущее время в часовом поясе пользователя
+// Если undo не nil, в той же транзакции сохраняется снимок задачи для отмены
+func (s *Storage) CompleteTask(id string, version int, now time.Time, undo *UndoRecord) (*Done, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var task Task
+	err = scanTask(tx.QueryRow(`SELECT `+taskColumns("")+` FROM scheduler WHERE id = ?`, id), &task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errTaskNotFound()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении задачи: %w", err)
+	}
+	if version != 0 && version != task.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	// Remaining == 1 означает, что выполняется последнее из count повторений
+	done := &Done{Task: &task}
+	var anchor string
+	if task.Repeat != "" && task.Remaining != 1 {
+		dates, err := holidayDates(tx)
+		if err != nil {
+			return nil, err
+		}
+		rule, err := nextdate.Parse(task.Repeat)
+		if err == nil {
+			done.NextDate, anchor, err = rule.NextShifted(now, task.Date, task.Anchor, nextdate.Holidays(dates))
+		}
+		if err != nil && !errors.Is(err, nextdate.ErrEnded) {
+			return nil, Invalid("repeat", "%v", err)
+		}
+	}
+
+	if done.NextDate == "" {
+		_, err = tx.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	} else {
+		err = tx.QueryRow(`UPDATE scheduler SET date = ?, anchor = ?, remaining = MAX(remaining - 1, 0),
+			version = version + 1 WHERE id = ? RETURNING version`, done.NextDate, anchor, id).Scan(&done.Version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при обновлении задачи: %w", err)
+	}
+
+	res, err := tx.Exec(`INSERT INTO task_completions (task_id, title, date, done_at, next_date) VALUES (?, ?, ?, ?, ?)`,
+		task.ID, task.Title, task.Date, now.UTC().Format(DoneAtFormat), done.NextDate)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при сохранении истории выполнения: %w", err)
+	}
+	if done.CompletionID, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("ошибка при получении ID записи истории: %w", err)
+	}
+
+	if undo != nil {
+		if err := saveUndo(tx, undo, &task, done.Version, done.CompletionID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return done, nil
+}
+
+// TaskHistory возвращает историю выполнения задачи, начиная с последних записей
+func (s *Storage) TaskHistory(taskID string) ([]*Completion, error) {
+	query := `SELECT id, task_id, title, date, done_at, next_date FROM task_completions
+		WHERE task_id = ? ORDER BY done_at DESC, id DESC`
+	return s.completions(query, taskID)
+}
+
+// Completions возвращает выполнения за период [from, to), начиная с первых записей
+// Нулевое значение from или to означает отсутствие ограничения
+func (s *Storage) Completions(from, to time.Time) ([]*Completion, error) {
+	query := `SELECT id, task_id, title, date, done_at, next_date FROM task_completions
+		WHERE (? = '' OR done_at >= ?) AND (? = '' OR done_at < ?) ORDER BY done_at ASC, id ASC`
+
+	var fromStr, toStr string
+	if !from.IsZero() {
+		fromStr = from.UTC().Format(DoneAtFormat)
+	}
+	if !to.IsZero() {
+		toStr = to.UTC().Format(DoneAtFormat)
+	}
+	return s.completions(query, fromStr, fromStr, toStr, toStr)
+}
+
+// completions выполняет запрос к task_completions и сканирует результат
+func (s *Storage) completions(query string, args ...interface{}) ([]*Completion, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении истории выполнения: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Completion{}
+	for rows.Next() {
+		var c Completion
+		var id, taskID int64
+
+		if err := rows.Scan(&id, &taskID, &c.Title, &c.Date, &c.DoneAt, &c.NextDate); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании истории выполнения: %w", err)
+		}
+
+		c.ID = strconv.FormatInt(id, 10)
+		c.TaskID = strconv.FormatInt(taskID, 10)
+		list = append(list, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+	return list, nil
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"os"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 
@@ -9,6 +10,7 @@ import (
 )
 
 // Schema содержит команды DDL для таблицы scheduler и индекса по date.
+// Используется как первая миграция, изменения схемы добавляются новыми миграциями.
 const Schema = `
 CREATE TABLE IF NOT EXISTS scheduler (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -26,8 +28,23 @@ var DefaultDbFile = "scheduler.db"
 // DateString — формат представления даты (YYYYMMDD).
 var DateString = "20060102"
 
-// DB — глобальный обработчик подключения к БД.
-var DB *sqlx.DB
+// Storage — хранилище задач в SQLite
+// Каждое хранилище работает со своим подключением, поэтому в одном процессе
+// можно держать несколько независимых экземпляров (например, в тестах)
+type Storage struct {
+	db *sqlx.DB
+}
+
+// New создаёт хранилище поверх открытого подключения, схема должна быть актуальной
+func New(conn *sqlx.DB) *Storage {
+	return &Storage{db: conn}
+}
+
+// dsnParams — параметры подключения к SQLite
+// Транзакции сразу берут блокировку записи (BEGIN IMMEDIATE), поэтому одновременные
+// транзакции, читающие и изменяющие одну задачу, выполняются по очереди;
+// ожидающее блокировку подключение ждёт до 5 секунд вместо ошибки «database is locked»
+const dsnParams = "_txlock=immediate&_pragma=busy_timeout(5000)"
 
 // getDbFile возвращает актуальный путь к файлу БД с учётом переменной окружения.
 // Читает переменную окружения TODO_DBFILE каждый раз при вызове
@@ -38,27 +55,30 @@ func getDbFile() string {
 	return DefaultDbFile
 }
 
-// Init открывает БД и при необходимости создаёт таблицу и индекс.
-func Init() error {
-	dbFile := getDbFile()
-	_, err := os.Stat(dbFile)
-	install := err != nil
+// Open открывает БД без применения миграций.
+func Open() (*sqlx.DB, error) {
+	file := getDbFile()
+	sep := "?"
+	if strings.Contains(file, "?") {
+		sep = "&"
+	}
+	return sqlx.Open("sqlite", file+sep+dsnParams)
+}
 
-	conn, err := sqlx.Open("sqlite", dbFile)
+// Init открывает БД, применяет неприменённые миграции схемы и возвращает хранилище.
+func Init() (*Storage, error) {
+	conn, err := Open()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if install {
-		if _, err := conn.Exec(Schema); err != nil {
-			_ = conn.Close()
-			return err
-		}
+	if _, err := Migrate(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
 	}
 
-	DB = conn
-	return nil
+	return New(conn), nil
 }
 
 // Close закрывает соединение с БД.
-func Close() { _ = DB.Close() }
+func (s *Storage) Close() error { return s.db.Close() }
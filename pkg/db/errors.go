@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Категории ошибок хранилища и проверки данных
+// Проверяются через errors.Is; текст для пользователя содержит сама ошибка (см. Error)
+var (
+	ErrNotFound   = errors.New("не найдено")
+	ErrConflict   = errors.New("конфликт")
+	ErrValidation = errors.New("некорректные данные")
+)
+
+// ErrVersionMismatch — задача изменилась после того, как клиент её прочитал
+// Частный случай ErrConflict: errors.Is(ErrVersionMismatch, ErrConflict) истинно
+var ErrVersionMismatch error = &Error{Kind: ErrConflict, Msg: "задача изменена после последнего чтения, получите её заново и повторите"}
+
+// Error — ошибка одной из категорий ErrNotFound, ErrConflict или ErrValidation
+// с собственным текстом для пользователя
+type Error struct {
+	Kind  error  // категория ошибки
+	Field string // поле или параметр запроса, к которому относится ошибка (может быть пустым)
+	Msg   string // текст ошибки
+}
+
+// Error возвращает текст ошибки
+func (e *Error) Error() string { return e.Msg }
+
+// Unwrap возвращает категорию ошибки, чтобы её можно было проверить через errors.Is
+func (e *Error) Unwrap() error { return e.Kind }
+
+// NotFound создаёт ошибку «не найдено» с текстом по формату
+func NotFound(format string, args ...any) error {
+	return &Error{Kind: ErrNotFound, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Conflict создаёт ошибку конфликта с текущим состоянием данных
+func Conflict(format string, args ...any) error {
+	return &Error{Kind: ErrConflict, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Invalid создаёт ошибку проверки значения поля field
+func Invalid(field, format string, args ...any) error {
+	return &Error{Kind: ErrValidation, Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+// errTaskNotFound — ошибка для отсутствующей задачи
+func errTaskNotFound() error {
+	return NotFound("задача не найдена")
+}
+
+// isUniqueViolation сообщает, что запись не добавлена из-за совпадения первичного ключа
+func isUniqueViolation(err error) bool {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || e.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
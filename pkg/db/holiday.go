@@ -0,0 +1,86 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Holiday представляет праздничный (нерабочий) день
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+// Holidays возвращает список праздников, отсортированный по дате
+func (s *Storage) Holidays() ([]*Holiday, error) {
+	return holidays(s.db)
+}
+
+// holidays читает список праздников через подключение или транзакцию q
+func holidays(q sqlx.Queryer) ([]*Holiday, error) {
+	rows, err := q.Query(`SELECT date, name FROM holidays ORDER BY date ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении списка праздников: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Holiday{}
+	for rows.Next() {
+		var h Holiday
+		if err := rows.Scan(&h.Date, &h.Name); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании праздника: %w", err)
+		}
+		list = append(list, &h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+	return list, nil
+}
+
+// HolidayDates возвращает множество дат праздников в формате 20060102
+func (s *Storage) HolidayDates() (map[string]bool, error) {
+	return holidayDates(s.db)
+}
+
+// holidayDates читает множество дат праздников через подключение или транзакцию q
+func holidayDates(q sqlx.Queryer) (map[string]bool, error) {
+	list, err := holidays(q)
+	if err != nil {
+		return nil, err
+	}
+	dates := make(map[string]bool, len(list))
+	for _, h := range list {
+		dates[h.Date] = true
+	}
+	return dates, nil
+}
+
+// SaveHolidays добавляет праздники в одной транзакции
+// Праздник с уже существующей датой заменяется; если replace — сначала удаляются все праздники
+func (s *Storage) SaveHolidays(list []*Holiday, replace bool) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if replace {
+		if _, err := tx.Exec(`DELETE FROM holidays`); err != nil {
+			return fmt.Errorf("ошибка при удалении праздников: %w", err)
+		}
+	}
+
+	for _, h := range list {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO holidays (date, name) VALUES (?, ?)`, h.Date, h.Name); err != nil {
+			return fmt.Errorf("ошибка при сохранении праздника %s: %w", h.Date, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return nil
+}
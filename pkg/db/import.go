@@ -0,0 +1,90 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Режимы импорта задач
+const (
+	ImportAppend  = "append"  // добавить задачи с новыми id
+	ImportReplace = "replace" // удалить все задачи и добавить импортируемые с их id
+	ImportUpsert  = "upsert"  // обновить задачи с совпадающим id, остальные добавить
+)
+
+// ImportTasks импортирует задачи в одной транзакции в указанном режиме
+// Метки задач заменяются метками из списка
+// Возвращает id задач в порядке входного списка; при ошибке изменения не сохраняются
+func (s *Storage) ImportTasks(tasks []*Task, mode string) ([]int64, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if mode == ImportReplace {
+		if _, err := tx.Exec(`DELETE FROM scheduler`); err != nil {
+			return nil, fmt.Errorf("ошибка при удалении задач: %w", err)
+		}
+	}
+
+	ids := make([]int64, 0, len(tasks))
+	for i, task := range tasks {
+		// В режиме append id из файла не используется
+		keepID := mode != ImportAppend && task.ID != ""
+
+		if keepID && mode == ImportUpsert {
+			res, err := tx.Exec(`UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, remaining = ?,
+				time = ?, duration = ?, priority = ?, anchor = ?, version = version + 1 WHERE id = ?`,
+				task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.Time, task.Duration,
+				task.Priority, task.Anchor, task.ID)
+			if err != nil {
+				return nil, fmt.Errorf("задача %d: ошибка при обновлении: %w", i+1, err)
+			}
+			count, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("задача %d: ошибка при проверке количества обновленных записей: %w", i+1, err)
+			}
+			if count > 0 {
+				id, _ := strconv.ParseInt(task.ID, 10, 64)
+				if err := setTaskTags(tx, id, task.Tags); err != nil {
+					return nil, fmt.Errorf("задача %d: %w", i+1, err)
+				}
+				ids = append(ids, id)
+				continue
+			}
+		}
+
+		var args []interface{}
+		query := `INSERT INTO scheduler (date, title, comment, repeat, remaining, time, duration, priority, created_at,
+			anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		args = append(args, task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.Time, task.Duration,
+			task.Priority, task.Created, task.Anchor)
+		if keepID {
+			query = `INSERT INTO scheduler (id, date, title, comment, repeat, remaining, time, duration, priority,
+				created_at, anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			args = append([]interface{}{task.ID}, args...)
+		}
+
+		res, err := tx.Exec(query, args...)
+		if isUniqueViolation(err) {
+			return nil, Conflict("задача %d: задача с id %s уже существует", i+1, task.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("задача %d: ошибка при добавлении: %w", i+1, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("задача %d: ошибка при получении ID: %w", i+1, err)
+		}
+		if err := setTaskTags(tx, id, task.Tags); err != nil {
+			return nil, fmt.Errorf("задача %d: %w", i+1, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration описывает одну версию схемы БД
+// Version — порядковый номер, Up — SQL-команды для перехода на эту версию
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+}
+
+// Migrations — упорядоченный список миграций
+// Новые миграции добавляются только в конец списка, уже выпущенные не меняются
+var Migrations = []Migration{
+	{Version: 1, Name: "create scheduler", Up: Schema},
+	{Version: 2, Name: "full-text search", Up: schemaFTS},
+	{Version: 3, Name: "task completions", Up: schemaCompletions},
+	{Version: 4, Name: "undo tokens", Up: schemaUndo},
+	{Version: 5, Name: "repeat count", Up: schemaRemaining},
+	{Version: 6, Name: "holidays", Up: schemaHolidays},
+	{Version: 7, Name: "task time", Up: schemaTime},
+	{Version: 8, Name: "task version", Up: schemaTaskVersion},
+	{Version: 9, Name: "tags", Up: schemaTags},
+	{Version: 10, Name: "task priority", Up: schemaPriority},
+	{Version: 11, Name: "undo version", Up: schemaUndoVersion},
+	{Version: 12, Name: "repeat anchor", Up: schemaAnchor},
+}
+
+// schemaFTS создаёт полнотекстовый индекс по title и comment
+// Индекс хранит только ссылки на строки scheduler и синхронизируется триггерами
+// Токенизатор unicode61 приводит кириллицу и латиницу к нижнему регистру
+const schemaFTS = `
+CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+    title,
+    comment,
+    content='scheduler',
+    content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_ai AFTER INSERT ON scheduler BEGIN
+    INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_ad AFTER DELETE ON scheduler BEGIN
+    INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+END;
+CREATE TRIGGER IF NOT EXISTS scheduler_fts_au AFTER UPDATE OF title, comment ON scheduler BEGIN
+    INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+    INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+END;
+INSERT INTO scheduler_fts(scheduler_fts) VALUES ('rebuild');
+`
+
+// schemaCompletions создаёт таблицу истории выполнения задач
+// Ссылка на задачу не внешний ключ: история сохраняется и после удаления задачи
+const schemaCompletions = `
+CREATE TABLE IF NOT EXISTS task_completions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL,
+    title VARCHAR(256) NOT NULL DEFAULT "",
+    date CHAR(8) NOT NULL DEFAULT "",
+    done_at VARCHAR(20) NOT NULL DEFAULT "",
+    next_date CHAR(8) NOT NULL DEFAULT ""
+);
+CREATE INDEX IF NOT EXISTS task_completions_task_id ON task_completions(task_id);
+CREATE INDEX IF NOT EXISTS task_completions_done_at ON task_completions(done_at);
+`
+
+// schemaUndo создаёт таблицу снимков задач для отмены выполнения и удаления
+const schemaUndo = `
+CREATE TABLE IF NOT EXISTS task_undo (
+    token VARCHAR(64) PRIMARY KEY,
+    task_id INTEGER NOT NULL,
+    date CHAR(8) NOT NULL DEFAULT "",
+    title VARCHAR(256) NOT NULL DEFAULT "",
+    comment TEXT NOT NULL DEFAULT "",
+    repeat VARCHAR(128) NOT NULL DEFAULT "",
+    completion_id INTEGER NOT NULL DEFAULT 0,
+    expires_at VARCHAR(20) NOT NULL DEFAULT ""
+);
+CREATE INDEX IF NOT EXISTS task_undo_expires_at ON task_undo(expires_at);
+`
+
+// schemaRemaining добавляет счётчик оставшихся повторений для правил с условием count
+const schemaRemaining = `
+ALTER TABLE scheduler ADD COLUMN remaining INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_undo ADD COLUMN remaining INTEGER NOT NULL DEFAULT 0;
+`
+
+// schemaHolidays создаёт таблицу праздничных (нерабочих) дней
+const schemaHolidays = `
+CREATE TABLE IF NOT EXISTS holidays (
+    date CHAR(8) PRIMARY KEY,
+    name VARCHAR(256) NOT NULL DEFAULT ""
+);
+`
+
+// schemaTime добавляет время начала (HH:MM, пустое — задача на весь день) и длительность в минутах
+// Индекс по дате и времени соответствует порядку списка задач
+const schemaTime = `
+ALTER TABLE scheduler ADD COLUMN time CHAR(5) NOT NULL DEFAULT "";
+ALTER TABLE scheduler ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_undo ADD COLUMN time CHAR(5) NOT NULL DEFAULT "";
+ALTER TABLE task_undo ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS scheduler_date_time ON scheduler(date, time, id);
+`
+
+// schemaTaskVersion добавляет номер версии задачи, который увеличивается при каждом изменении
+// По нему обнаруживаются одновременные изменения одной задачи (ETag и If-Match в API)
+const schemaTaskVersion = `
+ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE task_undo ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+`
+
+// schemaTags создаёт таблицу меток и таблицу связей задач с метками
+// Связи удалённой задачи удаляются триггером, метка без задач удаляется вместе с последней связью
+// В снимке для отмены метки хранятся одной строкой через запятую
+const schemaTags = `
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE
+);
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (task_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS task_tags_tag_id ON task_tags(tag_id);
+CREATE TRIGGER IF NOT EXISTS scheduler_tags_ad AFTER DELETE ON scheduler BEGIN
+    DELETE FROM task_tags WHERE task_id = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS task_tags_ad AFTER DELETE ON task_tags BEGIN
+    DELETE FROM tags WHERE id = old.tag_id AND NOT EXISTS (SELECT 1 FROM task_tags WHERE tag_id = old.tag_id);
+END;
+ALTER TABLE task_undo ADD COLUMN tags TEXT NOT NULL DEFAULT "";
+`
+
+// schemaPriority добавляет приоритет задачи и момент её создания (UTC в формате DoneAtFormat)
+// У задач, созданных до миграции, момент создания неизвестен и остаётся пустым
+const schemaPriority = `
+ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduler ADD COLUMN created_at VARCHAR(20) NOT NULL DEFAULT "";
+ALTER TABLE task_undo ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE task_undo ADD COLUMN created_at VARCHAR(20) NOT NULL DEFAULT "";
+CREATE INDEX IF NOT EXISTS scheduler_priority ON scheduler(priority, date, time, id);
+`
+
+// schemaUndoVersion добавляет в снимок версию задачи сразу после операции (0 — операция удалила задачу)
+// Отмена не затирает изменения, сделанные после операции: версия задачи должна совпадать с этой
+const schemaUndoVersion = `
+ALTER TABLE task_undo ADD COLUMN after_version INTEGER NOT NULL DEFAULT 0;
+`
+
+// schemaAnchor добавляет дату повторения до переноса на рабочий день (модификатор shift)
+// Пустая строка означает, что дата задачи не переносилась
+const schemaAnchor = `
+ALTER TABLE scheduler ADD COLUMN anchor CHAR(8) NOT NULL DEFAULT "";
+ALTER TABLE task_undo ADD COLUMN anchor CHAR(8) NOT NULL DEFAULT "";
+`
+
+// MigrationStatus описывает состояние схемы конкретной БД
+type MigrationStatus struct {
+	Current int         // версия схемы, записанная в БД
+	Latest  int         // последняя версия, известная программе
+	Pending []Migration // миграции, которые ещё не применены
+}
+
+// LatestVersion возвращает номер последней известной миграции
+func LatestVersion() int {
+	if len(Migrations) == 0 {
+		return 0
+	}
+	return Migrations[len(Migrations)-1].Version
+}
+
+// schemaVersion читает версию схемы из PRAGMA user_version
+func schemaVersion(conn *sqlx.DB) (int, error) {
+	var version int
+	if err := conn.Get(&version, `PRAGMA user_version`); err != nil {
+		return 0, fmt.Errorf("ошибка при чтении версии схемы: %w", err)
+	}
+	return version, nil
+}
+
+// Status возвращает текущую версию схемы и список неприменённых миграций
+func Status(conn *sqlx.DB) (MigrationStatus, error) {
+	current, err := schemaVersion(conn)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+
+	status := MigrationStatus{Current: current, Latest: LatestVersion()}
+	for _, m := range Migrations {
+		if m.Version > current {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Migrate применяет все неприменённые миграции по порядку
+// Каждая миграция выполняется в отдельной транзакции вместе с обновлением user_version
+// Если БД создана более новой версией программы, возвращает ошибку
+func Migrate(conn *sqlx.DB) (int, error) {
+	status, err := Status(conn)
+	if err != nil {
+		return 0, err
+	}
+	if status.Current > status.Latest {
+		return 0, fmt.Errorf("версия схемы БД (%d) новее, чем поддерживает программа (%d)",
+			status.Current, status.Latest)
+	}
+
+	for _, m := range status.Pending {
+		if err := applyMigration(conn, m); err != nil {
+			return 0, err
+		}
+	}
+	return len(status.Pending), nil
+}
+
+// applyMigration выполняет одну миграцию в транзакции
+func applyMigration(conn *sqlx.DB, m Migration) error {
+	tx, err := conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.Up); err != nil {
+		return fmt.Errorf("ошибка при применении миграции %d (%s): %w", m.Version, m.Name, err)
+	}
+	// PRAGMA не поддерживает плейсхолдеры, поэтому номер версии подставляем в текст
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.Version)); err != nil {
+		return fmt.Errorf("ошибка при обновлении версии схемы: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации миграции %d: %w", m.Version, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Порядок сортировки результатов поиска
+const (
+	OrderRank = "rank" // по релевантности (bm25), затем по дате
+	OrderDate = "date" // по дате, как в списке без поиска
+)
+
+// Маркеры, которыми выделяются совпадения в сниппете
+// SQLite вставляет в текст символы из области для частного использования Unicode,
+// после экранирования HTML они заменяются тегами <mark> (см. snippetHTML)
+const (
+	snippetOpen  = "\ue000"
+	snippetClose = "\ue001"
+)
+
+// snippetReplacer экранирует текст сниппета для HTML и заменяет маркеры совпадений тегами
+var snippetReplacer = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;",
+	snippetOpen, "<mark>", snippetClose, "</mark>",
+)
+
+// snippetHTML превращает сниппет с маркерами snippetOpen и snippetClose в безопасный HTML:
+// текст задачи экранируется, выделены только совпадения
+func snippetHTML(s string) string {
+	return snippetReplacer.Replace(s)
+}
+
+// ftsQuery преобразует пользовательскую строку поиска в запрос FTS5
+// Слова ищутся по префиксу, текст в двойных кавычках — как фраза целиком
+// Все слова и фразы должны присутствовать в задаче одновременно
+// Спецсимволы FTS5 и LIKE (*, %, _ и т.п.) не интерпретируются как операторы
+// Возвращает пустую строку, если в запросе нет ни одного слова
+func ftsQuery(search string) string {
+	var terms []string
+
+	for len(search) > 0 {
+		search = strings.TrimLeftFunc(search, unicode.IsSpace)
+		if search == "" {
+			break
+		}
+
+		// Фраза в кавычках
+		if search[0] == '"' {
+			end := strings.IndexByte(search[1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, search = search[1:], ""
+			} else {
+				phrase, search = search[1:end+1], search[end+2:]
+			}
+			if words := ftsWords(phrase); len(words) > 0 {
+				terms = append(terms, quoteFTS(strings.Join(words, " ")))
+			}
+			continue
+		}
+
+		// Отдельное слово до пробела или кавычки
+		end := strings.IndexFunc(search, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '"'
+		})
+		var word string
+		if end < 0 {
+			word, search = search, ""
+		} else {
+			word, search = search[:end], search[end:]
+		}
+		for _, w := range ftsWords(word) {
+			terms = append(terms, quoteFTS(w)+"*")
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// ftsWords разбивает текст на слова так же, как токенизатор unicode61:
+// разделителями считаются все символы, кроме букв и цифр
+func ftsWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// quoteFTS заключает строку в двойные кавычки по правилам FTS5
+func quoteFTS(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Режимы фильтра списка задач по нескольким меткам
+const (
+	TagModeAnd = "and" // задача отмечена всеми метками
+	TagModeOr  = "or"  // задача отмечена хотя бы одной из меток
+)
+
+// TagSeparator разделяет метки, записанные одной строкой
+// Поэтому запятая не может входить в имя метки
+const TagSeparator = ","
+
+// Tag описывает метку и количество задач, отмеченных ею
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// tagsColumn возвращает выражение SELECT со всеми метками задачи через TagSeparator
+// alias — псевдоним таблицы scheduler в запросе или пустая строка
+func tagsColumn(alias string) string {
+	if alias == "" {
+		alias = "scheduler"
+	}
+	return `COALESCE((SELECT group_concat(tg.name, '` + TagSeparator + `') FROM task_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id WHERE tt.task_id = ` + alias + `.id), '')`
+}
+
+// splitTags разбирает строку меток через TagSeparator в отсортированный список
+// Для пустой строки возвращает nil
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	tags := strings.Split(s, TagSeparator)
+	sort.Strings(tags)
+	return tags
+}
+
+// tagsCondition возвращает условие WHERE для отбора задач с метками tags
+// В режиме TagModeOr задаче достаточно одной метки из списка, иначе нужны все; метки не повторяются
+func tagsCondition(tags []string, mode string) (string, []interface{}) {
+	args := make([]interface{}, 0, len(tags)+1)
+	for _, name := range tags {
+		args = append(args, name)
+	}
+
+	cond := `s.id IN (SELECT tt.task_id FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tg.name IN (?` + strings.Repeat(`, ?`, len(tags)-1) + `) GROUP BY tt.task_id`
+	if mode != TagModeOr {
+		cond += ` HAVING count(*) = ?`
+		args = append(args, len(tags))
+	}
+	return cond + `)`, args
+}
+
+// setTaskTags заменяет метки задачи списком tags в транзакции tx
+// Недостающие метки создаются; метку, у которой не осталось задач, удаляет триггер task_tags_ad
+func setTaskTags(tx *sqlx.Tx, taskID int64, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("ошибка при удалении меток задачи: %w", err)
+	}
+
+	for _, name := range tags {
+		if _, err := tx.Exec(`INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+			return fmt.Errorf("ошибка при добавлении метки %s: %w", name, err)
+		}
+		_, err := tx.Exec(`INSERT OR IGNORE INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`,
+			taskID, name)
+		if err != nil {
+			return fmt.Errorf("ошибка при добавлении метки %s к задаче: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Tags возвращает метки, которыми отмечена хотя бы одна задача, с количеством задач
+// Список отсортирован по имени метки
+func (s *Storage) Tags() ([]*Tag, error) {
+	rows, err := s.db.Query(`SELECT tg.name, count(*) FROM tags tg JOIN task_tags tt ON tt.tag_id = tg.id
+		GROUP BY tg.id ORDER BY tg.name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении списка меток: %w", err)
+	}
+	defer rows.Close()
+
+	list := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("ошибка при сканировании метки: %w", err)
+		}
+		list = append(list, &tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+	return list, nil
+}
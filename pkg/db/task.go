@@ -1,9 +1,16 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"final_project/pkg/nextdate"
 )
 
 // Task представляет задачу в системе планировщика
@@ -13,72 +20,332 @@ type Task struct {
 	Title   string `json:"title"`
 	Comment string `json:"comment"`
 	Repeat  string `json:"repeat"`
+	// Time — время начала в формате 15:04; пустая строка означает задачу на весь день
+	Time string `json:"time,omitempty"`
+	// Duration — длительность в минутах, указывается только вместе со временем
+	Duration int `json:"duration,omitempty"`
+	// RepeatRule — разобранное правило повторения; при чтении из БД заполняется по Repeat,
+	// в запросе клиента используется, только если строка repeat не указана
+	RepeatRule *nextdate.Rule `json:"repeat_rule,omitempty"`
+	// RepeatText — описание правила на языке клиента, заполняется в ответах GET /api/task и /api/tasks
+	RepeatText string `json:"repeat_text,omitempty"`
+	// Remaining — сколько повторений осталось для правила с условием count, включая текущее
+	// Вычисляется сервером, значение из запроса клиента не используется
+	Remaining int `json:"remaining,omitempty"`
+	// Version — номер версии задачи, увеличивается при каждом изменении
+	// Передаётся клиенту в заголовке ETag; ожидаемая версия приходит только в If-Match, не в теле запроса
+	Version int `json:"version"`
+	// Tags — метки задачи в нижнем регистре, отсортированные по имени
+	Tags []string `json:"tags,omitempty"`
+	// Priority — приоритет от PriorityNone до PriorityMax, чем больше, тем срочнее задача
+	Priority int `json:"priority"`
+	// Created — момент создания задачи в UTC (формат DoneAtFormat)
+	// Заполняется сервером при добавлении, значение из запроса клиента не используется
+	Created string `json:"created,omitempty"`
+	// Anchor — дата повторения до переноса на рабочий день (модификатор shift) или пустая строка,
+	// если дата задачи не переносилась. Следующая дата вычисляется от неё, поэтому перенос не накапливается
+	Anchor string `json:"-"`
+	// Snippet — фрагмент текста в HTML: текст экранирован, совпадения выделены тегом <mark>
+	// Заполняется только при текстовом поиске
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Границы приоритета задачи
+const (
+	PriorityNone = 0 // приоритет не указан
+	PriorityMax  = 3 // самый высокий приоритет
+)
+
+// taskFields — колонки таблицы scheduler в порядке, ожидаемом scanTask
+var taskFields = []string{"id", "date", "title", "comment", "repeat", "remaining", "time", "duration", "version",
+	"priority", "created_at", "anchor"}
+
+// taskColumns возвращает список колонок задачи для SELECT, последней идёт строка меток задачи
+// alias — псевдоним таблицы scheduler в запросе или пустая строка
+func taskColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(taskFields, ", ") + ", " + tagsColumn(alias)
+	}
+	return alias + "." + strings.Join(taskFields, ", "+alias+".") + ", " + tagsColumn(alias)
 }
 
-// AddTask добавляет задачу в таблицу scheduler и возвращает ID созданной записи
-func AddTask(task *Task) (int64, error) {
+// rowScanner — общий интерфейс sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask считывает колонки taskColumns в задачу
+// extra — приёмники для дополнительных колонок, следующих за колонками задачи
+func scanTask(row rowScanner, task *Task, extra ...interface{}) error {
 	var id int64
+	var tags string
+	dest := append([]interface{}{&id, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Remaining,
+		&task.Time, &task.Duration, &task.Version, &task.Priority, &task.Created, &task.Anchor, &tags}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	task.ID = strconv.FormatInt(id, 10)
+	task.Tags = splitTags(tags)
+	// Правило, сохранённое до появления проверки, может не разобраться — тогда repeat_rule не выводится
+	task.RepeatRule, _ = nextdate.Parse(task.Repeat)
+	return nil
+}
 
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
+// AddTask добавляет задачу в таблицу scheduler вместе с её метками и возвращает ID созданной записи
+func (s *Storage) AddTask(task *Task) (int64, error) {
+	ids, err := s.AddTasks([]*Task{task})
 	if err != nil {
-		return 0, fmt.Errorf("ошибка при добавлении задачи: %w", err)
+		return 0, err
 	}
+	return ids[0], nil
+}
 
-	id, err = res.LastInsertId()
+// AddTasks добавляет несколько задач в одной транзакции и возвращает их ID
+// При ошибке не добавляется ни одна задача
+func (s *Storage) AddTasks(tasks []*Task) ([]int64, error) {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return 0, fmt.Errorf("ошибка при получении ID задачи: %w", err)
+		return nil, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO scheduler (date, title, comment, repeat, remaining, time, duration, priority, created_at,
+		anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		res, err := tx.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.Time,
+			task.Duration, task.Priority, task.Created, task.Anchor)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при добавлении задачи: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка при получении ID задачи: %w", err)
+		}
+		if err := setTaskTags(tx, id, task.Tags); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
 
-	return id, nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	return ids, nil
 }
 
-// Tasks возвращает список ближайших задач, отсортированных по дате
-// limit - максимальное количество возвращаемых записей
-// search - строка поиска (опционально)
-func Tasks(limit int, search string) ([]*Task, error) {
-	var query string
+// Значения фильтра по наличию правила повторения
+const (
+	RepeatAny = ""    // все задачи
+	RepeatYes = "yes" // только периодические задачи
+	RepeatNo  = "no"  // только разовые задачи
+)
+
+// Сортировки списка задач
+const (
+	SortDate     = "date"     // по дате и времени (по умолчанию)
+	SortPriority = "priority" // сначала задачи с более высоким приоритетом
+	SortTitle    = "title"    // по заголовку
+	SortCreated  = "created"  // сначала недавно созданные задачи
+)
+
+// taskSort описывает сортировку списка задач
+// Задачи с одинаковым ключом упорядочиваются по дате, времени и id, поэтому порядок устойчив
+type taskSort struct {
+	order string // выражение ORDER BY
+	// after — условие «задача после позиции курсора»; параметры: ключ, ключ, дата, время, id
+	// Пустое у сортировки по дате, где позицию задаёт тройка (date, time, id)
+	after string
+	key   func(*Task) string // значение ключа сортировки задачи для курсора
+}
+
+// taskSorts — сортировки списка задач
+var taskSorts = map[string]taskSort{
+	SortDate: {
+		order: `s.date ASC, s.time ASC, s.id ASC`,
+	},
+	SortPriority: {
+		order: `s.priority DESC, s.date ASC, s.time ASC, s.id ASC`,
+		after: `(s.priority < ? OR s.priority = ? AND (s.date, s.time, s.id) > (?, ?, ?))`,
+		key:   func(t *Task) string { return strconv.Itoa(t.Priority) },
+	},
+	SortTitle: {
+		order: `s.title COLLATE NOCASE ASC, s.date ASC, s.time ASC, s.id ASC`,
+		after: `(s.title COLLATE NOCASE > ? OR s.title COLLATE NOCASE = ? AND (s.date, s.time, s.id) > (?, ?, ?))`,
+		key:   func(t *Task) string { return t.Title },
+	},
+	SortCreated: {
+		order: `s.created_at DESC, s.date ASC, s.time ASC, s.id ASC`,
+		after: `(s.created_at < ? OR s.created_at = ? AND (s.date, s.time, s.id) > (?, ?, ?))`,
+		key:   func(t *Task) string { return t.Created },
+	},
+}
+
+// TaskFilter задаёт условия выборки списка задач
+type TaskFilter struct {
+	Limit   int      // максимальное количество возвращаемых записей
+	Search  string   // строка поиска: дата 02.01.2006 или текст (опционально)
+	Order   string   // порядок результатов текстового поиска: OrderRank или OrderDate
+	Sort    string   // сортировка списка: SortDate (по умолчанию), SortPriority, SortTitle или SortCreated
+	From    string   // минимальная дата задачи в формате 20060102 включительно (опционально)
+	To      string   // максимальная дата задачи в формате 20060102 включительно (опционально)
+	Repeat  string   // фильтр по правилу повторения: RepeatAny, RepeatYes или RepeatNo
+	Overdue string   // если задано, выбираются только задачи с датой раньше этой (20060102)
+	Tags    []string // метки без повторов; если заданы, выбираются только задачи с этими метками
+	TagMode string   // как сочетаются метки Tags: TagModeAnd (по умолчанию) или TagModeOr
+	Cursor  Cursor   // позиция, после которой начинается страница
+}
+
+// ByRank сообщает, упорядочиваются ли задачи по релевантности текстового поиска
+// Такой список листается по смещению Cursor.Offset, остальные — по позиции в списке
+func (f TaskFilter) ByRank() bool {
+	return f.Search != "" && !isDateFormat(f.Search) && f.Order != OrderDate && f.Sort == ""
+}
+
+// Cursor описывает позицию в списке задач
+// Страница продолжается после тройки (Date, Time, ID), а при сортировках, отличных от SortDate, —
+// после ключа сортировки Key и этой тройки, что устойчиво к добавлению новых задач;
+// при сортировке по релевантности используется смещение Offset
+type Cursor struct {
+	Sort   string // сортировка, для которой получена позиция (пустая у смещения)
+	Key    string
+	Date   string
+	Time   string
+	ID     int64
+	Offset int
+}
+
+// TasksPage — страница списка задач
+type TasksPage struct {
+	Tasks []*Task
+	Total int     // общее количество задач, подходящих под фильтр
+	Next  *Cursor // позиция следующей страницы или nil, если страница последняя
+}
+
+// Tasks возвращает страницу ближайших задач, по умолчанию отсортированных по дате и времени
+// Задачи на весь день идут в начале дня
+// Условия выборки, сортировка и позиция страницы задаются фильтром; явно заданная сортировка
+// заменяет упорядочивание результатов текстового поиска по релевантности
+func (s *Storage) Tasks(filter TaskFilter) (*TasksPage, error) {
+	from := `scheduler s`
+	var where []string
 	var args []interface{}
+	rank := false
 
-	if search == "" {
-		// Обычный запрос без поиска
-		query = `SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date ASC LIMIT ?`
-		args = []interface{}{limit}
-	} else {
-		// Проверяем, является ли search датой в формате 02.01.2006
-		if isDateFormat(search) {
-			// Поиск по дате
-			dateStr, err := convertDateFormat(search)
+	if filter.Search != "" {
+		if isDateFormat(filter.Search) {
+			// Поиск по дате в формате 02.01.2006
+			dateStr, err := convertDateFormat(filter.Search)
 			if err != nil {
 				return nil, fmt.Errorf("некорректный формат даты: %w", err)
 			}
-			query = `SELECT id, date, title, comment, repeat FROM scheduler WHERE date = ? ORDER BY date ASC LIMIT ?`
-			args = []interface{}{dateStr, limit}
+			where = append(where, `s.date = ?`)
+			args = append(args, dateStr)
 		} else {
-			// Поиск по заголовку и комментарию
-			searchPattern := "%" + search + "%"
-			query = `SELECT id, date, title, comment, repeat FROM scheduler WHERE title LIKE ? OR comment LIKE ? ORDER BY date ASC LIMIT ?`
-			args = []interface{}{searchPattern, searchPattern, limit}
+			// Полнотекстовый поиск по заголовку и комментарию
+			// Если в строке поиска нет ни одного слова, ничего не находим
+			match := ftsQuery(filter.Search)
+			if match == "" {
+				return &TasksPage{Tasks: []*Task{}}, nil
+			}
+			from = `scheduler_fts JOIN scheduler s ON s.id = scheduler_fts.rowid`
+			where = append(where, `scheduler_fts MATCH ?`)
+			args = append(args, match)
+			rank = filter.ByRank()
+		}
+	}
+
+	if filter.From != "" {
+		where = append(where, `s.date >= ?`)
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where = append(where, `s.date <= ?`)
+		args = append(args, filter.To)
+	}
+	switch filter.Repeat {
+	case RepeatYes:
+		where = append(where, `s.repeat <> ''`)
+	case RepeatNo:
+		where = append(where, `s.repeat = ''`)
+	}
+	if filter.Overdue != "" {
+		where = append(where, `s.date < ?`)
+		args = append(args, filter.Overdue)
+	}
+	if len(filter.Tags) > 0 {
+		cond, tagArgs := tagsCondition(filter.Tags, filter.TagMode)
+		where = append(where, cond)
+		args = append(args, tagArgs...)
+	}
+
+	cond := ""
+	if len(where) > 0 {
+		cond = ` WHERE ` + strings.Join(where, ` AND `)
+	}
+
+	// Общее количество считаем без учёта позиции страницы
+	var total int
+	if err := s.db.QueryRow(`SELECT count(*) FROM `+from+cond, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("ошибка при подсчёте задач: %w", err)
+	}
+
+	snippet := `''`
+	if strings.HasPrefix(from, `scheduler_fts`) {
+		snippet = `snippet(scheduler_fts, -1, ?, ?, '…', 10)`
+		args = append([]interface{}{snippetOpen, snippetClose}, args...)
+	}
+
+	sortName := filter.Sort
+	if _, ok := taskSorts[sortName]; !ok {
+		sortName = SortDate
+	}
+	order := taskSorts[sortName]
+
+	// Релевантность не даёт позиции в списке, поэтому результаты поиска листаем по смещению
+	query := `SELECT ` + taskColumns("s") + `, ` + snippet + ` FROM ` + from
+	if rank {
+		query += cond + ` ORDER BY bm25(scheduler_fts), ` + order.order + ` LIMIT ? OFFSET ?`
+		args = append(args, filter.Limit+1, filter.Cursor.Offset)
+	} else {
+		if c := filter.Cursor; c.Date != "" && c.Sort == sortName {
+			if order.after != "" {
+				where = append(where, order.after)
+				args = append(args, c.Key, c.Key, c.Date, c.Time, c.ID)
+			} else {
+				where = append(where, `(s.date, s.time, s.id) > (?, ?, ?)`)
+				args = append(args, c.Date, c.Time, c.ID)
+			}
+		}
+		if len(where) > 0 {
+			cond = ` WHERE ` + strings.Join(where, ` AND `)
 		}
+		query += cond + ` ORDER BY ` + order.order + ` LIMIT ?`
+		args = append(args, filter.Limit+1)
 	}
 
-	rows, err := DB.Query(query, args...)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при получении списка задач: %w", err)
 	}
 	defer rows.Close()
 
 	var tasks []*Task
+	more := false
 	for rows.Next() {
 		var task Task
-		var id int64
 
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
-		if err != nil {
+		if err := scanTask(rows, &task, &task.Snippet); err != nil {
 			return nil, fmt.Errorf("ошибка при сканировании задачи: %w", err)
 		}
+		task.Snippet = snippetHTML(task.Snippet)
+
+		// Лишняя запись означает, что есть следующая страница
+		if len(tasks) == filter.Limit {
+			more = true
+			break
+		}
 
-		task.ID = strconv.FormatInt(id, 10)
 		tasks = append(tasks, &task)
 	}
 
@@ -86,12 +353,58 @@ func Tasks(limit int, search string) ([]*Task, error) {
 		return nil, fmt.Errorf("ошибка при обработке результатов: %w", err)
 	}
 
+	page := &TasksPage{Tasks: tasks, Total: total}
+
+	// Если записей больше, чем помещается на страницу, формируем позицию следующей
+	if more {
+		if rank {
+			page.Next = &Cursor{Offset: filter.Cursor.Offset + len(tasks)}
+		} else {
+			last := tasks[len(tasks)-1]
+			id, _ := strconv.ParseInt(last.ID, 10, 64)
+			page.Next = &Cursor{Sort: sortName, Date: last.Date, Time: last.Time, ID: id}
+			if order.key != nil {
+				page.Next.Key = order.key(last)
+			}
+		}
+	}
+
 	// Если задач нет, возвращаем пустой слайс вместо nil
-	if tasks == nil {
-		tasks = []*Task{}
+	if page.Tasks == nil {
+		page.Tasks = []*Task{}
 	}
 
-	return tasks, nil
+	return page, nil
+}
+
+// EachTask вызывает fn для каждой задачи в порядке даты, времени и id
+// Задачи читаются построчно, поэтому таблица не загружается в память целиком
+// Если fn возвращает ошибку, обход прекращается и ошибка возвращается вызывающему
+func (s *Storage) EachTask(fn func(*Task) error) error {
+	query := `SELECT ` + taskColumns("") + ` FROM scheduler ORDER BY date ASC, time ASC, id ASC`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("ошибка при получении списка задач: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+
+		if err := scanTask(rows, &task); err != nil {
+			return fmt.Errorf("ошибка при сканировании задачи: %w", err)
+		}
+
+		if err := fn(&task); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка при обработке результатов: %w", err)
+	}
+	return nil
 }
 
 // isDateFormat проверяет, является ли строка датой в формате 02.01.2006
@@ -110,80 +423,152 @@ func convertDateFormat(dateStr string) (string, error) {
 }
 
 // GetTask возвращает задачу по указанному ID
-func GetTask(id string) (*Task, error) {
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?`
+func (s *Storage) GetTask(id string) (*Task, error) {
+	query := `SELECT ` + taskColumns("") + ` FROM scheduler WHERE id = ?`
 
 	var task Task
-	var taskID int64
 
-	err := DB.QueryRow(query, id).Scan(&taskID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	err := scanTask(s.db.QueryRow(query, id), &task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errTaskNotFound()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("задача не найдена")
+		return nil, fmt.Errorf("ошибка при получении задачи: %w", err)
 	}
 
-	task.ID = strconv.FormatInt(taskID, 10)
 	return &task, nil
 }
 
-// UpdateTask обновляет существующую задачу
-func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`
+// Fields — набор необязательных полей задачи
+// Поля из набора keep при обновлении задачи сохраняют прежние значения
+type Fields uint
+
+// Необязательные поля задачи
+const (
+	FieldTags     Fields = 1 << iota // метки
+	FieldPriority                    // приоритет
+	FieldTime                        // время начала вместе с длительностью
+)
 
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+// UpdateTask обновляет существующую задачу и увеличивает её версию
+// Если task.Version больше нуля, задача обновляется, только если её текущая версия совпадает,
+// иначе возвращается ErrVersionMismatch. После обновления task.Version содержит новую версию
+// Счётчик оставшихся повторений сбрасывается на task.Remaining, только если изменилось
+// число повторений count в правиле; иначе серия продолжается с сохранённого значения
+// Если дата и правило не изменились, а task.Anchor пуст, сохраняется прежняя дата до переноса
+// Метки, приоритет, время и длительность заменяются значениями из task, если соответствующие
+// FieldTags, FieldPriority и FieldTime не входят в keep
+func (s *Storage) UpdateTask(task *Task, keep Fields) error {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return fmt.Errorf("ошибка при обновлении задачи: %w", err)
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var date, repeat, anchor string
+	var remaining int
+	err = tx.QueryRow(`SELECT date, repeat, remaining, anchor FROM scheduler WHERE id = ?`, task.ID).
+		Scan(&date, &repeat, &remaining, &anchor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errTaskNotFound()
 	}
-
-	count, err := res.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("ошибка при проверке количества обновленных записей: %w", err)
+		return fmt.Errorf("ошибка при получении задачи: %w", err)
+	}
+	if repeatCount(repeat) == repeatCount(task.Repeat) {
+		task.Remaining = remaining
+	}
+	if task.Anchor == "" && date == task.Date && repeat == task.Repeat {
+		task.Anchor = anchor
 	}
 
-	if count == 0 {
-		return fmt.Errorf("задача не найдена")
+	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?,
+		time = CASE WHEN ? THEN time ELSE ? END, duration = CASE WHEN ? THEN duration ELSE ? END,
+		priority = CASE WHEN ? THEN priority ELSE ? END, remaining = ?, anchor = ?, version = version + 1
+		WHERE id = ? AND (? = 0 OR version = ?) RETURNING id, version`
+
+	var id int64
+	var version int
+	keepTime := keep&FieldTime != 0
+	err = tx.QueryRow(query, task.Date, task.Title, task.Comment, task.Repeat,
+		keepTime, task.Time, keepTime, task.Duration, keep&FieldPriority != 0, task.Priority,
+		task.Remaining, task.Anchor, task.ID, task.Version, task.Version).Scan(&id, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return unchanged(tx, task.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении задачи: %w", err)
+	}
+	if keep&FieldTags == 0 {
+		if err := setTaskTags(tx, id, task.Tags); err != nil {
+			return err
+		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
+	task.Version = version
 	return nil
 }
 
 // DeleteTask удаляет задачу по указанному ID
-func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
-
-	res, err := DB.Exec(query, id)
+// Если version больше нуля, задача удаляется, только если её текущая версия совпадает
+// Если undo не nil, в той же транзакции сохраняется снимок задачи для отмены
+func (s *Storage) DeleteTask(id string, version int, undo *UndoRecord) error {
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return fmt.Errorf("ошибка при удалении задачи: %w", err)
+		return fmt.Errorf("ошибка при начале транзакции: %w", err)
 	}
+	defer tx.Rollback()
 
-	count, err := res.RowsAffected()
+	var task Task
+	err = scanTask(tx.QueryRow(`SELECT `+taskColumns("")+` FROM scheduler WHERE id = ?`, id), &task)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errTaskNotFound()
+	}
 	if err != nil {
-		return fmt.Errorf("ошибка при проверке количества удаленных записей: %w", err)
+		return fmt.Errorf("ошибка при получении задачи: %w", err)
+	}
+	if version != 0 && version != task.Version {
+		return ErrVersionMismatch
 	}
 
-	if count == 0 {
-		return fmt.Errorf("задача не найдена")
+	if _, err := tx.Exec(`DELETE FROM scheduler WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("ошибка при удалении задачи: %w", err)
+	}
+	if undo != nil {
+		if err := saveUndo(tx, undo, &task, 0, 0); err != nil {
+			return err
+		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка при фиксации транзакции: %w", err)
+	}
 	return nil
 }
 
-// UpdateDate обновляет только дату задачи
-func UpdateDate(next string, id string) error {
-	query := `UPDATE scheduler SET date = ? WHERE id = ?`
-
-	res, err := DB.Exec(query, next, id)
-	if err != nil {
-		return fmt.Errorf("ошибка при обновлении даты задачи: %w", err)
+// repeatCount возвращает число повторений count правила repeat (0, если условия count нет)
+func repeatCount(repeat string) int {
+	rule, err := nextdate.Parse(repeat)
+	if err != nil || rule == nil {
+		return 0
 	}
+	return rule.Count
+}
 
-	count, err := res.RowsAffected()
+// unchanged объясняет, почему запрос с условием на id и версию не изменил ни одной записи:
+// задачи нет (ошибка «не найдена») или её версия уже другая (ErrVersionMismatch)
+// Проверка выполняется через подключение или транзакцию q, в которой выполнялся запрос
+func unchanged(q sqlx.Queryer, id string) error {
+	var exists bool
+	err := q.QueryRowx(`SELECT EXISTS (SELECT 1 FROM scheduler WHERE id = ?)`, id).Scan(&exists)
 	if err != nil {
-		return fmt.Errorf("ошибка при проверке количества обновленных записей: %w", err)
+		return fmt.Errorf("ошибка при проверке задачи: %w", err)
 	}
-
-	if count == 0 {
-		return fmt.Errorf("задача не найдена")
+	if !exists {
+		return errTaskNotFound()
 	}
-
-	return nil
+	return ErrVersionMismatch
 }
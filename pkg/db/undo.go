@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UndoRecord задаёт снимок для отмены выполнения или удаления задачи
+// Снимок записывается в той же транзакции, что и сама операция
+type UndoRecord struct {
+	Token   string    // токен, по которому операцию можно отменить
+	Now     time.Time // текущее время, по нему удаляются снимки с истёкшим сроком
+	Expires time.Time // момент, до которого операцию можно отменить
+}
+
+// saveUndo сохраняет в транзакции tx снимок задачи до операции
+// after — версия задачи сразу после операции (0, если операция удалила задачу)
+// completionID — запись истории, созданная выполнением (0, если задача удалялась)
+func saveUndo(tx *sqlx.Tx, undo *UndoRecord, task *Task, after int, completionID int64) error {
+	// Попутно удаляем снимки с истёкшим сроком
+	if _, err := tx.Exec(`DELETE FROM task_undo WHERE expires_at < ?`, undo.Now.UTC().Format(DoneAtFormat)); err != nil {
+		return fmt.Errorf("ошибка при удалении устаревших снимков: %w", err)
+	}
+
+	query := `INSERT INTO task_undo (token, task_id, date, title, comment, repeat, remaining, time, duration, version,
+		tags, priority, created_at, anchor, after_version, completion_id, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := tx.Exec(query, undo.Token, task.ID, task.Date, task.Title, task.Comment, task.Repeat, task.Remaining,
+		task.Time, task.Duration, task.Version, strings.Join(task.Tags, TagSeparator), task.Priority, task.Created,
+		task.Anchor, after, completionID, undo.Expires.UTC().Format(DoneAtFormat))
+	if err != nil {
+		return fmt.Errorf("ошибка при сохранении снимка задачи: %w", err)
+	}
+	return nil
+}
+
+// Undo восстанавливает задачу из снимка с указанным токеном
+// Задача получает тот же id, те же значения полей и метки, что были до операции,
+// запись истории выполнения удаляется; токен можно использовать только один раз
+// Если задачу изменили после операции, возвращается ErrVersionMismatch и снимок остаётся
+func (s *Storage) Undo(token string, now time.Time) (*Task, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при начале транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	var task Task
+	var id, completionID int64
+	var after int
+	var tags, expires string
+	err = tx.QueryRow(`SELECT task_id, date, title, comment, repeat, remaining, time, duration, version,
+		tags, priority, created_at, anchor, after_version, completion_id, expires_at FROM task_undo WHERE token = ?`,
+		token).Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Remaining, &task.Time,
+		&task.Duration, &task.Version, &tags, &task.Priority, &task.Created, &task.Anchor, &after, &completionID,
+		&expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, NotFound("операция для отмены не найдена")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при получении снимка задачи: %w", err)
+	}
+	if now.UTC().Format(DoneAtFormat) > expires {
+		return nil, NotFound("операция для отмены не найдена: срок отмены истёк")
+	}
+	task.ID = strconv.FormatInt(id, 10)
+	task.Tags = splitTags(tags)
+
+	// Если задача не менялась после операции, возвращаем ей прежние значения, иначе вставляем с тем же id
+	// Восстановление — тоже изменение, поэтому версия задачи увеличивается
+	err = tx.QueryRow(`UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, remaining = ?,
+		time = ?, duration = ?, priority = ?, anchor = ?, version = version + 1 WHERE id = ? AND version = ?
+		RETURNING version`,
+		task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.Time, task.Duration, task.Priority,
+		task.Anchor, task.ID, after).Scan(&task.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Задача есть, но с другой версией: её изменили после операции, и отмена затёрла бы эти изменения
+		var exists bool
+		if err := tx.Get(&exists, `SELECT EXISTS (SELECT 1 FROM scheduler WHERE id = ?)`, task.ID); err != nil {
+			return nil, fmt.Errorf("ошибка при проверке задачи: %w", err)
+		}
+		if exists {
+			return nil, ErrVersionMismatch
+		}
+		task.Version++
+		_, err = tx.Exec(`INSERT INTO scheduler (id, date, title, comment, repeat, remaining, time, duration, version,
+			priority, created_at, anchor) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			task.ID, task.Date, task.Title, task.Comment, task.Repeat, task.Remaining, task.Time, task.Duration,
+			task.Version, task.Priority, task.Created, task.Anchor)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при восстановлении задачи: %w", err)
+	}
+	if err := setTaskTags(tx, id, task.Tags); err != nil {
+		return nil, err
+	}
+
+	if completionID != 0 {
+		if _, err := tx.Exec(`DELETE FROM task_completions WHERE id = ?`, completionID); err != nil {
+			return nil, fmt.Errorf("ошибка при удалении записи истории: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM task_undo WHERE token = ?`, token); err != nil {
+		return nil, fmt.Errorf("ошибка при удалении снимка задачи: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка при фиксации отмены: %w", err)
+	}
+	return &task, nil
+}
@@ -0,0 +1,127 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Константы формата iCalendar (RFC 5545)
+const (
+	DateFormat     = "20060102"         // формат значения VALUE=DATE
+	DateTimeFormat = "20060102T150405Z" // формат значения DATE-TIME в UTC
+	LocalFormat    = "20060102T150405"  // формат значения DATE-TIME в местном («плавающем») времени
+	lineLimit      = 75                 // максимальная длина строки в октетах без учёта CRLF
+	prodID         = "-//final_project//Планировщик задач//RU"
+)
+
+// Event описывает одно событие календаря
+// Без времени начала событие занимает весь день
+type Event struct {
+	UID         string        // постоянный идентификатор события
+	Date        time.Time     // дата события; время учитывается, только если HasTime
+	HasTime     bool          // событие начинается в указанное в Date время
+	Duration    time.Duration // длительность события со временем (опционально)
+	Summary     string        // заголовок
+	Description string        // описание
+	RRule       string        // правило повторения RRULE без префикса "RRULE:" (опционально)
+}
+
+// Writer записывает календарь в формате iCalendar
+// Перед событиями нужно вызвать Begin, после них — End
+type Writer struct {
+	w   *bufio.Writer
+	now time.Time
+}
+
+// NewWriter создаёт Writer; now используется как DTSTAMP всех событий
+func NewWriter(w io.Writer, now time.Time) *Writer {
+	return &Writer{w: bufio.NewWriter(w), now: now.UTC()}
+}
+
+// Begin записывает заголовок календаря
+func (cw *Writer) Begin(name string) error {
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	if name != "" {
+		cw.line("X-WR-CALNAME:" + escape(name))
+	}
+	return cw.w.Flush()
+}
+
+// WriteEvent записывает событие VEVENT
+func (cw *Writer) WriteEvent(e Event) error {
+	cw.line("BEGIN:VEVENT")
+	cw.line("UID:" + escape(e.UID))
+	cw.line("DTSTAMP:" + cw.now.Format(DateTimeFormat))
+	if e.HasTime {
+		cw.line("DTSTART:" + e.Date.Format(LocalFormat))
+		if e.Duration > 0 {
+			cw.line("DURATION:PT" + strconv.Itoa(int(e.Duration.Minutes())) + "M")
+		}
+	} else {
+		cw.line("DTSTART;VALUE=DATE:" + e.Date.Format(DateFormat))
+		cw.line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format(DateFormat))
+	}
+	cw.line("SUMMARY:" + escape(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION:" + escape(e.Description))
+	}
+	if e.RRule != "" {
+		rrule := e.RRule
+		if e.HasTime {
+			// UNTIL должен иметь тот же тип, что и DTSTART, поэтому дату дополняем концом дня
+			rrule = untilRe.ReplaceAllString(rrule, "${1}T235959${2}")
+		}
+		cw.line("RRULE:" + rrule)
+	}
+	cw.line("END:VEVENT")
+	return cw.w.Flush()
+}
+
+// untilRe находит значение UNTIL в формате даты без времени
+var untilRe = regexp.MustCompile(`(UNTIL=\d{8})(;|$)`)
+
+// End записывает окончание календаря
+func (cw *Writer) End() error {
+	cw.line("END:VCALENDAR")
+	return cw.w.Flush()
+}
+
+// line записывает строку содержимого, перенося её по 75 октетов
+// Перенос не разрывает многобайтовые символы UTF-8
+func (cw *Writer) line(s string) {
+	limit := lineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		cw.w.WriteString(s[:cut])
+		cw.w.WriteString("\r\n ")
+		s = s[cut:]
+		// В продолжении первый октет занят пробелом
+		limit = lineLimit - 1
+	}
+	cw.w.WriteString(s)
+	cw.w.WriteString("\r\n")
+}
+
+// escape экранирует спецсимволы в значении типа TEXT
+func escape(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`;`, `\;`,
+		`,`, `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
@@ -0,0 +1,196 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Component описывает событие VEVENT или задачу VTODO, прочитанные из календаря
+type Component struct {
+	Kind        string // VEVENT или VTODO
+	UID         string
+	Summary     string
+	Description string
+	Date        string // дата начала (для VTODO без DTSTART — срок DUE) в формате 20060102
+	RRule       string // значение RRULE без префикса "RRULE:"
+	Line        int    // номер строки BEGIN компонента, для сообщений об ошибках
+	Err         error  // ошибка разбора компонента, остальные компоненты читаются дальше
+}
+
+// contentLine — строка содержимого после склейки продолжений
+type contentLine struct {
+	text string
+	num  int // номер первой исходной строки, для сообщений об ошибках
+}
+
+// property — строка содержимого, разобранная на имя, параметры и значение
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse читает календарь и возвращает все компоненты VEVENT и VTODO
+// Даты, заданные в UTC, переводятся в часовой пояс loc (пояс пользователя)
+// Ошибки в отдельных компонентах записываются в Component.Err,
+// ошибка возвращается только если сам поток не является календарём
+func Parse(r io.Reader, loc *time.Location) ([]Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		list    []Component
+		current *Component
+		inCal   bool
+		depth   int // вложенность внутри компонента (например, VALARM)
+	)
+
+	for _, l := range lines {
+		if strings.TrimSpace(l.text) == "" {
+			continue
+		}
+		p, err := parseLine(l.text)
+		if err != nil {
+			if current != nil && current.Err == nil {
+				current.Err = fmt.Errorf("строка %d: %w", l.num, err)
+			}
+			continue
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			inCal = true
+		case p.name == "BEGIN" && current == nil &&
+			(strings.EqualFold(p.value, "VEVENT") || strings.EqualFold(p.value, "VTODO")):
+			current = &Component{Kind: strings.ToUpper(p.value), Line: l.num}
+		case p.name == "BEGIN" && current != nil:
+			depth++
+		case p.name == "END" && current != nil && depth > 0:
+			depth--
+		case p.name == "END" && current != nil:
+			if current.Date == "" && current.Err == nil {
+				current.Err = fmt.Errorf("не указана дата DTSTART")
+			}
+			list = append(list, *current)
+			current = nil
+		case current != nil && depth == 0:
+			if err := current.set(p, loc); err != nil && current.Err == nil {
+				current.Err = fmt.Errorf("строка %d: %w", l.num, err)
+			}
+		}
+	}
+
+	if !inCal {
+		return nil, fmt.Errorf("данные не являются календарём iCalendar")
+	}
+	return list, nil
+}
+
+// set заполняет поле компонента значением свойства
+func (c *Component) set(p property, loc *time.Location) error {
+	switch p.name {
+	case "UID":
+		c.UID = unescape(p.value)
+	case "SUMMARY":
+		c.Summary = unescape(p.value)
+	case "DESCRIPTION":
+		c.Description = unescape(p.value)
+	case "RRULE":
+		c.RRule = p.value
+	case "DTSTART", "DUE":
+		// DTSTART имеет приоритет над сроком DUE
+		if p.name == "DUE" && c.Date != "" {
+			return nil
+		}
+		date, err := parseDate(p.value, loc)
+		if err != nil {
+			return fmt.Errorf("некорректная дата %s: %s", p.name, p.value)
+		}
+		c.Date = date
+	}
+	return nil
+}
+
+// parseDate переводит значение DATE или DATE-TIME в дату 20060102
+// Время в UTC переводится в часовой пояс loc,
+// время с TZID и плавающее время берётся как записано
+func parseDate(v string, loc *time.Location) (string, error) {
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(DateTimeFormat, v)
+		if err != nil {
+			return "", err
+		}
+		return t.In(loc).Format(DateFormat), nil
+	}
+	if len(v) < len(DateFormat) {
+		return "", fmt.Errorf("слишком короткое значение")
+	}
+	t, err := time.Parse(DateFormat, v[:len(DateFormat)])
+	if err != nil {
+		return "", err
+	}
+	return t.Format(DateFormat), nil
+}
+
+// unfold читает строки содержимого, склеивая перенесённые продолжения
+func unfold(r io.Reader) ([]contentLine, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []contentLine
+	for num := 1; sc.Scan(); num++ {
+		l := strings.TrimSuffix(sc.Text(), "\r")
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += l[1:]
+			continue
+		}
+		lines = append(lines, contentLine{text: l, num: num})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения календаря: %w", err)
+	}
+	return lines, nil
+}
+
+// parseLine разбирает строку вида NAME;PARAM=VALUE:значение
+func parseLine(l string) (property, error) {
+	p := property{params: map[string]string{}}
+
+	// Ищем двоеточие, отделяющее значение, пропуская двоеточия в кавычках
+	quoted := false
+	colon := -1
+	for i := 0; i < len(l) && colon < 0; i++ {
+		switch l[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return p, fmt.Errorf("нет двоеточия в строке %q", l)
+	}
+
+	head := strings.Split(l[:colon], ";")
+	p.name = strings.ToUpper(head[0])
+	for _, param := range head[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	p.value = l[colon+1:]
+	return p, nil
+}
+
+// unescape снимает экранирование значения типа TEXT
+func unescape(s string) string {
+	r := strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, "\n", `\N`, "\n")
+	return r.Replace(s)
+}
@@ -0,0 +1,274 @@
+package ical
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"final_project/pkg/nextdate"
+)
+
+// weekDays — сокращения дней недели RFC 5545, индекс 1 — понедельник, 7 — воскресенье
+var weekDays = [...]string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// RRule переводит правило повторения планировщика в значение RRULE
+// Поддерживаемые правила:
+//   - "d <число>" → FREQ=DAILY;INTERVAL=<число>
+//   - "y" → FREQ=YEARLY
+//   - "w <дни недели>" → FREQ=WEEKLY;BYDAY=...
+//   - "m <дни месяца> [месяцы]" → FREQ=MONTHLY;BYMONTHDAY=...[;BYMONTH=...]
+//   - "mw <номер>:<день недели> [месяцы]" → FREQ=MONTHLY;BYDAY=2TU,-1FR[;BYMONTH=...]
+//
+// Условие окончания "until <дата>" переводится в UNTIL, "count <число>" — в COUNT.
+// remaining — оставшееся число повторений задачи; если оно больше нуля,
+// COUNT отсчитывается от него, так как DTSTART — уже текущая дата задачи
+// Для пустого правила возвращает пустую строку
+func RRule(repeat string, remaining int) (string, error) {
+	rule, err := nextdate.Parse(repeat)
+	if err != nil || rule == nil {
+		return "", err
+	}
+
+	var rrule string
+	switch rule.Kind {
+	case nextdate.KindYear:
+		rrule = "FREQ=YEARLY"
+	case nextdate.KindDays:
+		rrule = "FREQ=DAILY;INTERVAL=" + strconv.Itoa(rule.Interval)
+	case nextdate.KindWeek:
+		byDay := make([]string, len(rule.WeekDays))
+		for i, d := range rule.WeekDays {
+			byDay[i] = weekDays[d]
+		}
+		rrule = "FREQ=WEEKLY;BYDAY=" + strings.Join(byDay, ",")
+	case nextdate.KindMonth:
+		rrule = "FREQ=MONTHLY;BYMONTHDAY=" + joinInts(rule.MonthDays)
+	case nextdate.KindMonthWeekDay:
+		byDay := make([]string, len(rule.MonthWeekDays))
+		for i, wd := range rule.MonthWeekDays {
+			byDay[i] = strconv.Itoa(wd.Nth) + weekDays[wd.WeekDay]
+		}
+		rrule = "FREQ=MONTHLY;BYDAY=" + strings.Join(byDay, ",")
+	default:
+		return "", fmt.Errorf("правило %q не имеет аналога в RRULE", repeat)
+	}
+	if len(rule.Months) > 0 {
+		rrule += ";BYMONTH=" + joinInts(rule.Months)
+	}
+	// Перенос на рабочий день зависит от календаря праздников и в RRULE не выражается
+	if rule.Shift {
+		return "", fmt.Errorf("перенос на рабочий день не имеет аналога в RRULE")
+	}
+
+	switch {
+	case rule.Until != "" && rule.Count > 0:
+		return "", fmt.Errorf("сочетание until и count не имеет аналога в RRULE")
+	case rule.Until != "":
+		rrule += ";UNTIL=" + rule.Until
+	case rule.Count > 0:
+		count := rule.Count
+		if remaining > 0 {
+			count = remaining
+		}
+		rrule += ";COUNT=" + strconv.Itoa(count)
+	}
+	return rrule, nil
+}
+
+// parseList разбирает список чисел через запятую и проверяет диапазон [min, max]
+func parseList(s string, min, max int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	nums := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		if n < min || n > max {
+			return nil, fmt.Errorf("значение %d вне диапазона от %d до %d", n, min, max)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// joinInts объединяет числа через запятую
+func joinInts(nums []int) string {
+	parts := make([]string, len(nums))
+	for i, n := range nums {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Repeat переводит значение RRULE в правило повторения планировщика
+// dstart — дата начала события в формате 20060102, из неё берутся
+// недостающие день недели и день месяца; UNTIL в UTC переводится в часовой пояс loc
+// Если у правила нет аналога в планировщике, возвращает ошибку с причиной
+func Repeat(rrule string, dstart string, loc *time.Location) (string, error) {
+	if rrule == "" {
+		return "", nil
+	}
+
+	start, err := time.Parse(DateFormat, dstart)
+	if err != nil {
+		return "", fmt.Errorf("некорректная дата начала: %s", dstart)
+	}
+
+	parts := map[string]string{}
+	for _, p := range strings.Split(rrule, ";") {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok {
+			return "", fmt.Errorf("некорректная часть RRULE: %q", p)
+		}
+		parts[strings.ToUpper(k)] = strings.ToUpper(v)
+	}
+
+	// Условие окончания переводим в суффикс правила планировщика
+	var suffix string
+	if v, ok := parts["UNTIL"]; ok {
+		until, err := parseDate(v, loc)
+		if err != nil {
+			return "", fmt.Errorf("некорректный UNTIL: %s", v)
+		}
+		suffix += " until " + until
+		delete(parts, "UNTIL")
+	}
+	if v, ok := parts["COUNT"]; ok {
+		count, err := strconv.Atoi(v)
+		if err != nil || count < 1 {
+			return "", fmt.Errorf("некорректный COUNT: %s", v)
+		}
+		suffix += " count " + strconv.Itoa(count)
+		delete(parts, "COUNT")
+	}
+
+	rep, err := repeatRule(parts, start)
+	if err != nil {
+		return "", err
+	}
+	return rep + suffix, nil
+}
+
+// repeatRule переводит части RRULE без COUNT и UNTIL в правило планировщика
+func repeatRule(parts map[string]string, start time.Time) (string, error) {
+	var err error
+	freq := parts["FREQ"]
+	interval := 1
+	if v, ok := parts["INTERVAL"]; ok {
+		interval, err = strconv.Atoi(v)
+		if err != nil || interval < 1 {
+			return "", fmt.Errorf("некорректный INTERVAL: %s", v)
+		}
+	}
+
+	// Части, которые допускает каждая частота
+	allowed := map[string][]string{
+		"DAILY":   {"FREQ", "INTERVAL", "WKST"},
+		"WEEKLY":  {"FREQ", "INTERVAL", "WKST", "BYDAY"},
+		"MONTHLY": {"FREQ", "INTERVAL", "WKST", "BYMONTHDAY", "BYMONTH", "BYDAY"},
+		"YEARLY":  {"FREQ", "INTERVAL", "WKST", "BYMONTH", "BYMONTHDAY"},
+	}
+	keys, ok := allowed[freq]
+	if !ok {
+		return "", fmt.Errorf("частота FREQ=%s не поддерживается", freq)
+	}
+	for _, k := range slices.Sorted(maps.Keys(parts)) {
+		if !slices.Contains(keys, k) {
+			return "", fmt.Errorf("часть %s не поддерживается для FREQ=%s", k, freq)
+		}
+	}
+
+	switch freq {
+	case "DAILY":
+		if interval > 400 {
+			return "", fmt.Errorf("интервал больше 400 дней не поддерживается")
+		}
+		return "d " + strconv.Itoa(interval), nil
+
+	case "WEEKLY":
+		byDay, ok := parts["BYDAY"]
+		if !ok {
+			// Без BYDAY событие повторяется через interval недель в день недели начала
+			if interval*7 > 400 {
+				return "", fmt.Errorf("интервал больше 400 дней не поддерживается")
+			}
+			return "d " + strconv.Itoa(interval*7), nil
+		}
+		if interval != 1 {
+			return "", fmt.Errorf("повторение через %d недели по дням недели не поддерживается", interval)
+		}
+		days := make([]int, 0)
+		for _, d := range strings.Split(byDay, ",") {
+			n := slices.Index(weekDays[:], d)
+			if n < 1 {
+				return "", fmt.Errorf("некорректный день недели BYDAY: %s", d)
+			}
+			days = append(days, n)
+		}
+		return "w " + joinInts(days), nil
+
+	case "MONTHLY":
+		if interval != 1 {
+			return "", fmt.Errorf("повторение через %d месяца не поддерживается", interval)
+		}
+		rep := "m " + strconv.Itoa(start.Day())
+		if v, ok := parts["BYDAY"]; ok {
+			// BYDAY в месячном правиле должен содержать номер: 2TU, -1FR
+			if _, ok := parts["BYMONTHDAY"]; ok {
+				return "", fmt.Errorf("сочетание BYDAY и BYMONTHDAY не поддерживается")
+			}
+			var items []string
+			for _, d := range strings.Split(v, ",") {
+				if len(d) < 3 {
+					return "", fmt.Errorf("некорректный день недели BYDAY: %s", d)
+				}
+				n, err := strconv.Atoi(d[:len(d)-2])
+				wd := slices.Index(weekDays[:], d[len(d)-2:])
+				if err != nil || n == 0 || n < -5 || n > 5 || wd < 1 {
+					return "", fmt.Errorf("день недели BYDAY=%s не поддерживается", d)
+				}
+				items = append(items, strconv.Itoa(n)+":"+strconv.Itoa(wd))
+			}
+			rep = "mw " + strings.Join(items, ",")
+		}
+		if v, ok := parts["BYMONTHDAY"]; ok {
+			days, err := parseList(v, -31, 31)
+			if err != nil {
+				return "", fmt.Errorf("некорректный BYMONTHDAY: %s", v)
+			}
+			for _, d := range days {
+				if d == 0 || d < -2 {
+					return "", fmt.Errorf("день месяца %d не поддерживается", d)
+				}
+			}
+			rep = "m " + joinInts(days)
+		}
+		if v, ok := parts["BYMONTH"]; ok {
+			months, err := parseList(v, 1, 12)
+			if err != nil {
+				return "", fmt.Errorf("некорректный BYMONTH: %s", v)
+			}
+			rep += " " + joinInts(months)
+		}
+		return rep, nil
+
+	case "YEARLY":
+		if interval != 1 {
+			return "", fmt.Errorf("повторение через %d лет не поддерживается", interval)
+		}
+		// BYMONTH и BYMONTHDAY допустимы, только если совпадают с датой начала
+		if v, ok := parts["BYMONTH"]; ok && v != strconv.Itoa(int(start.Month())) {
+			return "", fmt.Errorf("BYMONTH=%s не совпадает с датой начала", v)
+		}
+		if v, ok := parts["BYMONTHDAY"]; ok && v != strconv.Itoa(start.Day()) {
+			return "", fmt.Errorf("BYMONTHDAY=%s не совпадает с датой начала", v)
+		}
+		return "y", nil
+	}
+
+	return "", fmt.Errorf("частота FREQ=%s не поддерживается", freq)
+}
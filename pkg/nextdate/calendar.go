@@ -0,0 +1,40 @@
+package nextdate
+
+import (
+	"time"
+)
+
+// Calendar определяет, какие дни считаются рабочими
+// Используется правилом "b" и модификатором переноса "shift"
+type Calendar interface {
+	IsWorkday(date time.Time) bool
+}
+
+// Weekends — календарь, в котором нерабочими считаются только суббота и воскресенье
+type Weekends struct{}
+
+// IsWorkday возвращает true для дней с понедельника по пятницу
+func (Weekends) IsWorkday(date time.Time) bool {
+	wd := date.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+// Holidays — календарь с выходными и праздничными днями
+// Ключ — дата праздника в формате 20060102
+type Holidays map[string]bool
+
+// IsWorkday возвращает true для будних дней, которые не являются праздниками
+func (h Holidays) IsWorkday(date time.Time) bool {
+	return Weekends{}.IsWorkday(date) && !h[date.Format("20060102")]
+}
+
+// nextWorkday возвращает ближайший рабочий день, начиная с date включительно
+// Если рабочий день не найден за год, возвращает исходную дату
+func nextWorkday(date time.Time, cal Calendar) time.Time {
+	for d, i := date, 0; i < 366; i, d = i+1, d.AddDate(0, 0, 1) {
+		if cal.IsWorkday(d) {
+			return d
+		}
+	}
+	return date
+}
@@ -0,0 +1,258 @@
+package nextdate
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Языки описания правил повторения
+const (
+	LangRu = "ru"
+	LangEn = "en"
+)
+
+// Describe возвращает описание правила на естественном языке, например
+// "в 1-й и последний день марта и июня" или "on the 1st and last day of March and June"
+// lang — LangRu или LangEn, для остальных значений используется русский язык
+// Для nil-правила возвращает пустую строку
+func (r *Rule) Describe(lang string) string {
+	if r == nil {
+		return ""
+	}
+	if lang == LangEn {
+		return describeEn(r)
+	}
+	return describeRu(r)
+}
+
+// Русские названия для описания правил
+var (
+	// дни недели во множественном числе дательного падежа: "по понедельникам"
+	ruWeekDaysDat = [...]string{"", "понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+	// дни недели в винительном падеже: "в первую среду"
+	ruWeekDaysAcc = [...]string{"", "понедельник", "вторник", "среду", "четверг", "пятницу", "субботу", "воскресенье"}
+	// род дня недели: 0 — мужской, 1 — женский, 2 — средний
+	ruWeekDayGender = [...]int{0, 0, 0, 1, 0, 1, 1, 2}
+	// месяцы в родительном падеже: "марта и июня"
+	ruMonthsGen = [...]string{"", "января", "февраля", "марта", "апреля", "мая", "июня",
+		"июля", "августа", "сентября", "октября", "ноября", "декабря"}
+	// порядковые числительные в винительном падеже по родам
+	ruNth = map[int][3]string{
+		1:  {"первый", "первую", "первое"},
+		2:  {"второй", "вторую", "второе"},
+		3:  {"третий", "третью", "третье"},
+		4:  {"четвёртый", "четвёртую", "четвёртое"},
+		5:  {"пятый", "пятую", "пятое"},
+		-1: {"последний", "последнюю", "последнее"},
+		-2: {"предпоследний", "предпоследнюю", "предпоследнее"},
+	}
+)
+
+// describeRu описывает правило на русском языке
+func describeRu(r *Rule) string {
+	var text string
+	switch r.Kind {
+	case KindYear:
+		text = "каждый год"
+	case KindDays:
+		text = ruEvery(r.Interval, "день", "дня", "дней")
+	case KindBusinessDays:
+		text = ruEvery(r.Interval, "рабочий день", "рабочих дня", "рабочих дней")
+	case KindWeek:
+		days := make([]string, len(r.WeekDays))
+		for i, d := range r.WeekDays {
+			days[i] = ruWeekDaysDat[d]
+		}
+		text = "по " + joinWords(days, "и")
+	case KindMonth:
+		days := make([]string, len(r.MonthDays))
+		for i, d := range r.MonthDays {
+			switch d {
+			case -1:
+				days[i] = "последний"
+			case -2:
+				days[i] = "предпоследний"
+			default:
+				days[i] = strconv.Itoa(d) + "-й"
+			}
+		}
+		text = "в " + joinWords(days, "и") + " день " + ruMonths(r.Months)
+	case KindMonthWeekDay:
+		days := make([]string, len(r.MonthWeekDays))
+		for i, wd := range r.MonthWeekDays {
+			nth := ruNth[wd.Nth][ruWeekDayGender[wd.WeekDay]]
+			if nth == "" {
+				// -3..-5 описываем как "третий с конца"
+				nth = ruNth[-wd.Nth][ruWeekDayGender[wd.WeekDay]] + " с конца"
+			}
+			prep := "в "
+			if strings.HasPrefix(nth, "втор") {
+				prep = "во "
+			}
+			days[i] = prep + nth + " " + ruWeekDaysAcc[wd.WeekDay]
+		}
+		text = joinWords(days, "и") + " " + ruMonths(r.Months)
+	default:
+		return r.String()
+	}
+
+	if r.Shift {
+		text += ", с переносом на следующий рабочий день, если дата выпадает на выходной"
+	}
+	if r.Until != "" {
+		text += ", до " + formatUntil(r.Until, "02.01.2006")
+	}
+	if r.Count > 0 {
+		text += ", " + strconv.Itoa(r.Count) + " " + ruPlural(r.Count, "раз", "раза", "раз")
+	}
+	return text
+}
+
+// ruEvery описывает интервал: "каждый день", "каждые 2 дня", "каждый 21 день"
+func ruEvery(n int, one, few, many string) string {
+	if n == 1 {
+		return "каждый " + one
+	}
+	if word := ruPlural(n, one, few, many); word != one {
+		return "каждые " + strconv.Itoa(n) + " " + word
+	}
+	return "каждый " + strconv.Itoa(n) + " " + one
+}
+
+// ruPlural выбирает форму слова для числа n: 1 день, 2 дня, 5 дней
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	default:
+		return many
+	}
+}
+
+// ruMonths описывает список месяцев: "марта и июня" или "каждого месяца"
+func ruMonths(months []int) string {
+	if len(months) == 0 {
+		return "каждого месяца"
+	}
+	names := make([]string, len(months))
+	for i, m := range months {
+		names[i] = ruMonthsGen[m]
+	}
+	return joinWords(names, "и")
+}
+
+// Английские названия для описания правил
+var enNth = map[int]string{
+	1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth",
+	-1: "last", -2: "second-to-last", -3: "third-to-last", -4: "fourth-to-last", -5: "fifth-to-last",
+}
+
+// describeEn описывает правило на английском языке
+func describeEn(r *Rule) string {
+	var text string
+	switch r.Kind {
+	case KindYear:
+		text = "every year"
+	case KindDays:
+		text = enEvery(r.Interval, "day")
+	case KindBusinessDays:
+		text = enEvery(r.Interval, "business day")
+	case KindWeek:
+		days := make([]string, len(r.WeekDays))
+		for i, d := range r.WeekDays {
+			days[i] = time.Weekday(d % 7).String()
+		}
+		text = "every " + joinWords(days, "and")
+	case KindMonth:
+		days := make([]string, len(r.MonthDays))
+		for i, d := range r.MonthDays {
+			switch d {
+			case -1:
+				days[i] = "last"
+			case -2:
+				days[i] = "second-to-last"
+			default:
+				days[i] = enOrdinal(d)
+			}
+		}
+		text = "on the " + joinWords(days, "and") + " day of " + enMonths(r.Months)
+	case KindMonthWeekDay:
+		days := make([]string, len(r.MonthWeekDays))
+		for i, wd := range r.MonthWeekDays {
+			days[i] = enNth[wd.Nth] + " " + time.Weekday(wd.WeekDay%7).String()
+		}
+		text = "on the " + joinWords(days, "and") + " of " + enMonths(r.Months)
+	default:
+		return r.String()
+	}
+
+	if r.Shift {
+		text += ", moved to the next business day if it falls on a day off"
+	}
+	if r.Until != "" {
+		text += ", until " + formatUntil(r.Until, "January 2, 2006")
+	}
+	switch {
+	case r.Count == 1:
+		text += ", once"
+	case r.Count > 1:
+		text += ", " + strconv.Itoa(r.Count) + " times"
+	}
+	return text
+}
+
+// enEvery описывает интервал: "every day", "every 3 days"
+func enEvery(n int, unit string) string {
+	if n == 1 {
+		return "every " + unit
+	}
+	return "every " + strconv.Itoa(n) + " " + unit + "s"
+}
+
+// enOrdinal возвращает порядковое числительное: 1st, 2nd, 3rd, 11th, 21st
+func enOrdinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// enMonths описывает список месяцев: "March and June" или "every month"
+func enMonths(months []int) string {
+	if len(months) == 0 {
+		return "every month"
+	}
+	names := make([]string, len(months))
+	for i, m := range months {
+		names[i] = time.Month(m).String()
+	}
+	return joinWords(names, "and")
+}
+
+// joinWords соединяет слова через запятую, последние два — союзом: "a, b и c"
+func joinWords(words []string, and string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " " + and + " " + words[len(words)-1]
+}
+
+// formatUntil переводит дату until из формата 20060102 в формат layout
+func formatUntil(until, layout string) string {
+	t, err := time.Parse("20060102", until)
+	if err != nil {
+		return until
+	}
+	return t.Format(layout)
+}
@@ -1,12 +1,65 @@
 package nextdate
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// ErrEnded возвращается NextDate, когда следующая дата выходит за границу until
+// и серия повторений закончилась
+var ErrEnded = errors.New("серия повторений завершена")
+
+// End описывает условие окончания серии повторений
+type End struct {
+	Until string // последняя допустимая дата в формате 20060102 или пустая строка
+	Count int    // общее число повторений или 0, если не ограничено
+}
+
+// SplitEnd отделяет от правила повторения необязательное условие окончания
+// "until <дата>" и/или "count <число>", например "d 7 until 20261231" или "w 1 count 10"
+// Возвращает правило без условия и разобранное условие
+func SplitEnd(repeat string) (string, End, error) {
+	var end End
+	rep := strings.Fields(repeat)
+
+	for len(rep) >= 2 {
+		key, value := rep[len(rep)-2], rep[len(rep)-1]
+		switch {
+		case key == "until" && end.Until == "":
+			if _, err := time.Parse("20060102", value); err != nil {
+				return "", end, fmt.Errorf("некорректная дата until: %s", value)
+			}
+			end.Until = value
+		case key == "count" && end.Count == 0:
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return "", end, fmt.Errorf("число повторений count должно быть положительным, получено: %s", value)
+			}
+			end.Count = count
+		default:
+			return joinRule(repeat, rep, end)
+		}
+		rep = rep[:len(rep)-2]
+	}
+
+	if len(rep) == 0 && (end.Until != "" || end.Count != 0) {
+		return "", end, fmt.Errorf("не указано правило повторения перед условием окончания")
+	}
+	return joinRule(repeat, rep, end)
+}
+
+// joinRule собирает правило без условия окончания
+// Если условия нет, правило возвращается без изменений, чтобы проверка формата осталась прежней
+func joinRule(repeat string, rep []string, end End) (string, End, error) {
+	if end.Until == "" && end.Count == 0 {
+		return repeat, end, nil
+	}
+	return strings.Join(rep, " "), end, nil
+}
+
 // NextDate вычисляет следующую дату для задачи в соответствии с правилом повторения
 // Принимает:
 //   - now: время, от которого ищется ближайшая дата
@@ -18,179 +71,29 @@ import (
 //   - "y": ежегодное повторение
 //   - "w <дни недели>": повторение в указанные дни недели (1-7, где 1-понедельник, 7-воскресенье)
 //   - "m <дни месяца> [месяцы]": повторение в указанные дни месяца (1-31, -1, -2)
+//   - "mw <номер>:<день недели> [месяцы]": повторение в N-й день недели месяца,
+//     например "mw 2:2,-1:5" — вторник второй недели и последняя пятница месяца
+//     (номер 1-5 от начала месяца или -1..-5 от конца, день недели 1-7)
+//   - "b <число>": повторение через указанное количество рабочих дней (1-400)
+//
+// К правилам "y", "m" и "mw" можно добавить модификатор "shift": если дата выпадает
+// на выходной или праздник, она переносится на ближайший следующий рабочий день
+//
+// К любому правилу можно добавить условие окончания "until <дата>" и/или "count <число>".
+// Если следующая дата позже until, возвращается ErrEnded. Условие count
+// NextDate только проверяет: оставшееся число повторений хранится вместе с задачей
+//
+// Рабочими днями считаются дни с понедельника по пятницу; чтобы учесть праздники,
+// используйте NextDateCal
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
-	// Если правило не указано, возвращаем пустую строку
-	if strings.TrimSpace(repeat) == "" {
-		return "", nil
-	}
+	return NextDateCal(now, dstart, repeat, Weekends{})
+}
 
-	// Парсим исходную дату
-	date, err := time.Parse("20060102", dstart)
+// NextDateCal вычисляет следующую дату как NextDate, определяя рабочие дни по календарю cal
+func NextDateCal(now time.Time, dstart string, repeat string, cal Calendar) (string, error) {
+	rule, err := Parse(repeat)
 	if err != nil {
-		return "", fmt.Errorf("некорректная дата dstart: %w", err)
-	}
-
-	// Разбираем строку с правилом повторения в слайс
-	rep := strings.Split(repeat, " ")
-
-	// Если только один символ и это не год, то плохое правило
-	if rep[0] != "y" && len(rep) < 2 {
-		return "", fmt.Errorf("неизвестное правило повторения: %s", rep[0])
-	}
-
-	// Повторяем год
-	if rep[0] == "y" {
-		for {
-			date = date.AddDate(1, 0, 0)
-			if date.After(now) {
-				break
-			}
-		}
-		return date.Format("20060102"), nil
-	}
-
-	// Повторяем дни
-	if rep[0] == "d" {
-		interval, err := strconv.Atoi(rep[1])
-		if err != nil {
-			return "", fmt.Errorf("невалидное число дней: %s", rep[1])
-		}
-		if interval > 400 || interval < 1 {
-			return "", fmt.Errorf("интервал должен быть от 1 до 400, получено: %d", interval)
-		}
-		for {
-			date = date.AddDate(0, 0, interval)
-			if date.After(now) {
-				break
-			}
-		}
-		return date.Format("20060102"), nil
-	}
-
-	// Повторяем дни недели
-	if rep[0] == "w" {
-		weekDays := strings.Split(rep[1], ",")
-
-		for {
-			// Приращаем дату
-			date = date.AddDate(0, 0, 1)
-			weekDayMatch := false
-			for _, v := range weekDays {
-				weekDayNum, err := strconv.Atoi(strings.TrimSpace(v))
-				if err != nil {
-					return "", fmt.Errorf("невалидный день недели: %s", v)
-				}
-				// Если левый номер дня
-				if weekDayNum > 7 || weekDayNum < 0 {
-					return "", fmt.Errorf("день недели должен быть от 1 до 7, получено: %d", weekDayNum)
-				}
-				// Воскресенье в буржуйский формат
-				if weekDayNum == 7 {
-					weekDayNum = 0
-				}
-				// Если нашли
-				if date.Weekday() == time.Weekday(weekDayNum) {
-					weekDayMatch = true
-					break
-				}
-			}
-			// Все совпало - прекращаем поиск
-			if date.After(now) && weekDayMatch {
-				break
-			}
-		}
-		return date.Format("20060102"), nil
-	}
-
-	// Повторяем дни месяца
-	if rep[0] == "m" {
-		monthDays := strings.Split(rep[1], ",")
-		// Парсим дни месяца (сохраняем оригинальные строки для проверки формата)
-		dayNums := make([]int, 0, len(monthDays))
-		for _, d := range monthDays {
-			d = strings.TrimSpace(d)
-			day, err := strconv.Atoi(d)
-			if err != nil {
-				return "", fmt.Errorf("невалидный день месяца: %s", d)
-			}
-			if day > 31 || day < -2 || day == 0 {
-				return "", fmt.Errorf("день месяца должен быть от 1 до 31, -1 или -2, получено: %d", day)
-			}
-			dayNums = append(dayNums, day)
-		}
-
-		// Парсим месяцы, если указаны
-		monthNums := make(map[int]bool)
-		if len(rep) > 2 {
-			monthStrings := strings.Split(rep[2], ",")
-			for _, m := range monthStrings {
-				month, err := strconv.Atoi(strings.TrimSpace(m))
-				if err != nil {
-					return "", fmt.Errorf("невалидный месяц: %s", m)
-				}
-				if month < 1 || month > 12 {
-					return "", fmt.Errorf("месяц должен быть от 1 до 12, получено: %d", month)
-				}
-				monthNums[month] = true
-			}
-		}
-
-		// Нормализуем даты (убираем время)
-		dateOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-		nowOnly := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
-
-		// Начинаем с исходной даты или текущей (что больше)
-		searchDate := dateOnly
-		if nowOnly.After(dateOnly) || nowOnly.Equal(dateOnly) {
-			searchDate = nowOnly.AddDate(0, 0, 1) // Начинаем со следующего дня после now
-		}
-
-		// Ищем ближайшую подходящую дату
-		maxIterations := 800 // Ограничение на количество итераций
-		for i := 0; i < maxIterations; i++ {
-			// Проверяем, подходит ли текущая дата
-			dayMatch := false
-			for _, targetDay := range dayNums {
-				// Определяем целевой день
-				var actualDay int
-				if targetDay == -1 {
-					// Последний день месяца
-					lastDay := time.Date(searchDate.Year(), searchDate.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
-					actualDay = lastDay
-				} else if targetDay == -2 {
-					// Предпоследний день месяца
-					lastDay := time.Date(searchDate.Year(), searchDate.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
-					actualDay = lastDay - 1
-				} else {
-					actualDay = targetDay
-				}
-
-				// Проверяем, что этот день существует в месяце
-				maxDayInMonth := time.Date(searchDate.Year(), searchDate.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
-				if actualDay <= maxDayInMonth && searchDate.Day() == actualDay {
-					dayMatch = true
-					break
-				}
-			}
-
-			// Проверяем месяц, если задана фильтрация по месяцам
-			monthMatch := true
-			if len(monthNums) > 0 {
-				currentMonth := int(searchDate.Month())
-				monthMatch = monthNums[currentMonth]
-			}
-
-			// Если всё совпало и дата после now - возвращаем
-			if dayMatch && monthMatch && searchDate.After(nowOnly) {
-				return searchDate.Format("20060102"), nil
-			}
-
-			// Переходим к следующему дню
-			searchDate = searchDate.AddDate(0, 0, 1)
-		}
-
-		return "", fmt.Errorf("не удалось найти следующую дату")
+		return "", err
 	}
-
-	return "", nil
+	return rule.Next(now, dstart, cal)
 }
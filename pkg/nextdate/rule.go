@@ -0,0 +1,466 @@
+package nextdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Виды правил повторения
+const (
+	KindDays         = "d"  // через N дней
+	KindYear         = "y"  // ежегодно
+	KindWeek         = "w"  // по дням недели
+	KindMonth        = "m"  // по дням месяца
+	KindMonthWeekDay = "mw" // в N-й день недели месяца
+	KindBusinessDays = "b"  // через N рабочих дней
+)
+
+// MonthWeekDay — N-й день недели месяца из правила "mw"
+type MonthWeekDay struct {
+	Nth     int `json:"nth"`      // 1..5 — номер от начала месяца, -1..-5 — от конца
+	WeekDay int `json:"week_day"` // день недели, 1 — понедельник, 7 — воскресенье
+}
+
+// Rule — разобранное правило повторения
+// Заполнены только поля, относящиеся к виду правила Kind
+type Rule struct {
+	Kind          string         `json:"kind"`
+	Interval      int            `json:"interval,omitempty"`        // d, b
+	WeekDays      []int          `json:"week_days,omitempty"`       // w
+	MonthDays     []int          `json:"month_days,omitempty"`      // m
+	MonthWeekDays []MonthWeekDay `json:"month_week_days,omitempty"` // mw
+	Months        []int          `json:"months,omitempty"`          // m, mw; пустой список — любой месяц
+	Shift         bool           `json:"shift,omitempty"`           // y, m, mw: перенос на рабочий день
+	Until         string         `json:"until,omitempty"`
+	Count         int            `json:"count,omitempty"`
+}
+
+// Parse разбирает и проверяет правило повторения в строковом виде (см. NextDate)
+// Для пустой строки возвращает nil без ошибки
+func Parse(repeat string) (*Rule, error) {
+	if strings.TrimSpace(repeat) == "" {
+		return nil, nil
+	}
+
+	// Отделяем условие окончания серии
+	base, end, err := SplitEnd(repeat)
+	if err != nil {
+		return nil, err
+	}
+	rule := &Rule{Until: end.Until, Count: end.Count}
+
+	rep := strings.Fields(base)
+
+	// Отделяем модификатор переноса на рабочий день
+	if len(rep) > 1 && rep[len(rep)-1] == "shift" {
+		if rep[0] != KindYear && rep[0] != KindMonth && rep[0] != KindMonthWeekDay {
+			return nil, fmt.Errorf("перенос на рабочий день поддерживается только для правил y, m и mw")
+		}
+		rule.Shift = true
+		rep = rep[:len(rep)-1]
+	}
+
+	rule.Kind = rep[0]
+	switch rule.Kind {
+	case KindYear:
+		if len(rep) != 1 {
+			return nil, fmt.Errorf("лишние параметры в правиле: %s", base)
+		}
+		return rule, nil
+	case KindDays, KindBusinessDays, KindWeek, KindMonth, KindMonthWeekDay:
+	default:
+		return nil, fmt.Errorf("неизвестное правило повторения: %s", rep[0])
+	}
+
+	// Остальным правилам нужен хотя бы один параметр
+	if len(rep) < 2 {
+		return nil, fmt.Errorf("не указаны параметры правила повторения: %s", rep[0])
+	}
+	maxFields := 2
+	if rule.Kind == KindMonth || rule.Kind == KindMonthWeekDay {
+		maxFields = 3
+	}
+	if len(rep) > maxFields {
+		return nil, fmt.Errorf("лишние параметры в правиле: %s", base)
+	}
+
+	switch rule.Kind {
+	case KindDays, KindBusinessDays:
+		interval, err := strconv.Atoi(rep[1])
+		if err != nil {
+			return nil, fmt.Errorf("невалидное число дней: %s", rep[1])
+		}
+		if interval > 400 || interval < 1 {
+			return nil, fmt.Errorf("интервал должен быть от 1 до 400, получено: %d", interval)
+		}
+		rule.Interval = interval
+
+	case KindWeek:
+		for _, v := range strings.Split(rep[1], ",") {
+			day, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("невалидный день недели: %s", v)
+			}
+			if day > 7 || day < 1 {
+				return nil, fmt.Errorf("день недели должен быть от 1 до 7, получено: %d", day)
+			}
+			rule.WeekDays = append(rule.WeekDays, day)
+		}
+
+	case KindMonth:
+		for _, v := range strings.Split(rep[1], ",") {
+			day, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("невалидный день месяца: %s", v)
+			}
+			if day > 31 || day < -2 || day == 0 {
+				return nil, fmt.Errorf("день месяца должен быть от 1 до 31, -1 или -2, получено: %d", day)
+			}
+			rule.MonthDays = append(rule.MonthDays, day)
+		}
+
+	case KindMonthWeekDay:
+		if rule.MonthWeekDays, err = parseMonthWeekDays(rep[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Месяцы для правил m и mw
+	if len(rep) > 2 {
+		for _, v := range strings.Split(rep[2], ",") {
+			month, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("невалидный месяц: %s", v)
+			}
+			if month < 1 || month > 12 {
+				return nil, fmt.Errorf("месяц должен быть от 1 до 12, получено: %d", month)
+			}
+			rule.Months = append(rule.Months, month)
+		}
+		if err := checkReachable(rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return rule, nil
+}
+
+// maxMonthDays — наибольшее число дней в каждом месяце с учётом високосного февраля
+var maxMonthDays = [12]int{31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// checkReachable проверяет, что правило m или mw с указанными месяцами даёт хоть какую-то дату
+// Правило вроде "m 30 2" не даёт ни одной даты; пятый день недели в феврале бывает
+// только в високосный год раз в 28 лет, поэтому такие правила тоже отклоняются
+func checkReachable(rule *Rule) error {
+	for _, month := range rule.Months {
+		for _, day := range rule.MonthDays {
+			if day < 0 || day <= maxMonthDays[month-1] {
+				return nil
+			}
+		}
+		for _, wd := range rule.MonthWeekDays {
+			if month != 2 || wd.Nth != 5 && wd.Nth != -5 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("в месяцах %s нет подходящих дней, правило никогда не повторится", joinInts(rule.Months))
+}
+
+// parseMonthWeekDays разбирает список вида "2:2,-1:5" из правила "mw"
+func parseMonthWeekDays(s string) ([]MonthWeekDay, error) {
+	var list []MonthWeekDay
+	for _, item := range strings.Split(s, ",") {
+		nthStr, dayStr, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return nil, fmt.Errorf("ожидается <номер>:<день недели>, получено: %s", item)
+		}
+		nth, err := strconv.Atoi(nthStr)
+		if err != nil || nth == 0 || nth > 5 || nth < -5 {
+			return nil, fmt.Errorf("номер недели должен быть от 1 до 5 или от -1 до -5, получено: %s", nthStr)
+		}
+		day, err := strconv.Atoi(dayStr)
+		if err != nil || day < 1 || day > 7 {
+			return nil, fmt.Errorf("день недели должен быть от 1 до 7, получено: %s", dayStr)
+		}
+		list = append(list, MonthWeekDay{Nth: nth, WeekDay: day})
+	}
+	return list, nil
+}
+
+// String возвращает правило в строковом виде, который принимает Parse
+func (r *Rule) String() string {
+	if r == nil {
+		return ""
+	}
+
+	parts := []string{r.Kind}
+	switch r.Kind {
+	case KindDays, KindBusinessDays:
+		parts = append(parts, strconv.Itoa(r.Interval))
+	case KindWeek:
+		parts = append(parts, joinInts(r.WeekDays))
+	case KindMonth:
+		parts = append(parts, joinInts(r.MonthDays))
+	case KindMonthWeekDay:
+		items := make([]string, len(r.MonthWeekDays))
+		for i, wd := range r.MonthWeekDays {
+			items[i] = strconv.Itoa(wd.Nth) + ":" + strconv.Itoa(wd.WeekDay)
+		}
+		parts = append(parts, strings.Join(items, ","))
+	}
+	if len(r.Months) > 0 {
+		parts = append(parts, joinInts(r.Months))
+	}
+	if r.Shift {
+		parts = append(parts, "shift")
+	}
+	if r.Until != "" {
+		parts = append(parts, "until", r.Until)
+	}
+	if r.Count > 0 {
+		parts = append(parts, "count", strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, " ")
+}
+
+// joinInts соединяет числа через запятую
+func joinInts(nums []int) string {
+	items := make([]string, len(nums))
+	for i, n := range nums {
+		items[i] = strconv.Itoa(n)
+	}
+	return strings.Join(items, ",")
+}
+
+// Next вычисляет следующую дату после now и dstart по правилу
+// Учитывается только календарная дата now в её часовом поясе, поэтому now нужно передавать
+// в часовом поясе пользователя. Рабочие дни определяются по календарю cal (nil — только выходные)
+// Для nil-правила возвращает пустую строку, при выходе за until — ErrEnded
+func (r *Rule) Next(now time.Time, dstart string, cal Calendar) (string, error) {
+	next, _, err := r.next(now, dstart, cal)
+	return next, err
+}
+
+// NextShifted вычисляет следующую дату задачи с датой date, перенесённой на рабочий день с даты anchor
+// (пустой anchor — дата не переносилась). Следующая дата позже now и date, но отсчитывается от anchor,
+// поэтому перенос не накапливается: "y shift" от 20210101 даёт 20220103, затем 20230102, а не 20230103
+// Возвращает также новую дату до переноса или пустую строку, если перенос не понадобился
+func (r *Rule) NextShifted(now time.Time, date, anchor string, cal Calendar) (next, nextAnchor string, err error) {
+	if anchor == "" {
+		anchor = date
+	}
+	// Дату date уже занимает текущее повторение, следующее должно быть позже неё
+	if date > now.Format("20060102") {
+		if now, err = time.Parse("20060102", date); err != nil {
+			return "", "", fmt.Errorf("некорректная дата: %w", err)
+		}
+	}
+	next, nextAnchor, err = r.next(now, anchor, cal)
+	if nextAnchor == next {
+		nextAnchor = ""
+	}
+	return next, nextAnchor, err
+}
+
+// next вычисляет следующую дату по правилу и дату до переноса на рабочий день
+func (r *Rule) next(now time.Time, dstart string, cal Calendar) (next, anchor string, err error) {
+	if r == nil {
+		return "", "", nil
+	}
+	if cal == nil {
+		cal = Weekends{}
+	}
+
+	// Парсим исходную дату
+	date, err := time.Parse("20060102", dstart)
+	if err != nil {
+		return "", "", fmt.Errorf("некорректная дата dstart: %w", err)
+	}
+
+	// Даты задач не привязаны к часовому поясу, поэтому «сегодня» тоже переводим
+	// в полночь UTC того же календарного дня
+	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch r.Kind {
+	case KindYear:
+		date = nextYear(now, date)
+	case KindDays:
+		date = nextDays(now, date, r.Interval)
+	case KindBusinessDays:
+		date = nextBusinessDays(now, date, r.Interval, cal)
+	case KindWeek:
+		date = nextWeekDay(now, date, r.WeekDays)
+	case KindMonth:
+		date, err = nextMonthDay(now, date, r.MonthDays, r.Months)
+	case KindMonthWeekDay:
+		date, err = nextMonthWeekDay(now, date, r.MonthWeekDays, r.Months)
+	default:
+		err = fmt.Errorf("неизвестное правило повторения: %s", r.Kind)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	anchor = date.Format("20060102")
+	if r.Shift {
+		date = nextWorkday(date, cal)
+	}
+	next = date.Format("20060102")
+	if r.Until != "" && next > r.Until {
+		return "", "", ErrEnded
+	}
+	return next, anchor, nil
+}
+
+// nextYear повторяет дату ежегодно
+func nextYear(now, date time.Time) time.Time {
+	for {
+		date = date.AddDate(1, 0, 0)
+		if date.After(now) {
+			return date
+		}
+	}
+}
+
+// nextDays повторяет дату через interval дней
+func nextDays(now, date time.Time, interval int) time.Time {
+	for {
+		date = date.AddDate(0, 0, interval)
+		if date.After(now) {
+			return date
+		}
+	}
+}
+
+// nextBusinessDays повторяет дату через interval рабочих дней
+func nextBusinessDays(now, date time.Time, interval int, cal Calendar) time.Time {
+	for {
+		// Отсчитываем interval рабочих дней после текущей даты
+		for n := 0; n < interval; {
+			date = date.AddDate(0, 0, 1)
+			if cal.IsWorkday(date) {
+				n++
+			}
+		}
+		if date.After(now) {
+			return date
+		}
+	}
+}
+
+// nextWeekDay ищет ближайший из указанных дней недели
+func nextWeekDay(now, date time.Time, weekDays []int) time.Time {
+	for {
+		// Приращаем дату
+		date = date.AddDate(0, 0, 1)
+		if !date.After(now) {
+			continue
+		}
+		for _, day := range weekDays {
+			// Воскресенье в буржуйский формат
+			if date.Weekday() == time.Weekday(day%7) {
+				return date
+			}
+		}
+	}
+}
+
+// maxSearchDays ограничивает перебор дней в правилах m и mw
+// Сочетания дня недели, числа и високосного года повторяются с циклом в 28 лет,
+// а 29 февраля бывает с перерывом до 8 лет (на рубеже веков), поэтому правило,
+// прошедшее checkReachable, находит дату в пределах 28 лет
+const maxSearchDays = 28 * 366
+
+// nextMonthDay ищет ближайший из указанных дней месяца (-1 — последний, -2 — предпоследний)
+func nextMonthDay(now, date time.Time, monthDays, months []int) (time.Time, error) {
+	// Нормализуем даты (убираем время)
+	dateOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	nowOnly := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Начинаем с исходной даты или со следующего дня после now (что больше)
+	searchDate := dateOnly
+	if !nowOnly.Before(dateOnly) {
+		searchDate = nowOnly.AddDate(0, 0, 1)
+	}
+
+	// Ищем ближайшую подходящую дату
+	for i := 0; i < maxSearchDays; i++ {
+		lastDay := time.Date(searchDate.Year(), searchDate.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+		dayMatch := false
+		for _, targetDay := range monthDays {
+			// Отрицательные дни отсчитываются от конца месяца
+			actualDay := targetDay
+			if targetDay < 0 {
+				actualDay = lastDay + targetDay + 1
+			}
+			if searchDate.Day() == actualDay {
+				dayMatch = true
+				break
+			}
+		}
+
+		if dayMatch && matchMonth(searchDate, months) && searchDate.After(nowOnly) {
+			return searchDate, nil
+		}
+
+		// Переходим к следующему дню
+		searchDate = searchDate.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}, fmt.Errorf("не удалось найти следующую дату")
+}
+
+// nextMonthWeekDay ищет ближайший N-й день недели месяца строго после исходной даты и после now
+func nextMonthWeekDay(now, date time.Time, weekDays []MonthWeekDay, months []int) (time.Time, error) {
+	// Нормализуем даты (убираем время)
+	searchDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	nowOnly := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if searchDate.Before(nowOnly) {
+		searchDate = nowOnly
+	}
+
+	for i := 0; i < maxSearchDays; i++ {
+		searchDate = searchDate.AddDate(0, 0, 1)
+
+		if !matchMonth(searchDate, months) {
+			continue
+		}
+		for _, wd := range weekDays {
+			if matchMonthWeekDay(searchDate, wd) {
+				return searchDate, nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("не удалось найти следующую дату")
+}
+
+// matchMonth проверяет, что месяц даты входит в список; пустой список означает любой месяц
+func matchMonth(date time.Time, months []int) bool {
+	if len(months) == 0 {
+		return true
+	}
+	for _, m := range months {
+		if int(date.Month()) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMonthWeekDay проверяет, что дата — N-й указанный день недели своего месяца
+func matchMonthWeekDay(date time.Time, wd MonthWeekDay) bool {
+	// Воскресенье в буржуйский формат
+	if date.Weekday() != time.Weekday(wd.WeekDay%7) {
+		return false
+	}
+	if wd.Nth > 0 {
+		return (date.Day()-1)/7+1 == wd.Nth
+	}
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return -((lastDay-date.Day())/7 + 1) == wd.Nth
+}
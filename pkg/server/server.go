@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	"final_project/pkg/api"
+	"final_project/pkg/db"
 )
 
 // Константы для настройки порта сервера
@@ -15,6 +21,22 @@ const (
 	envPortKey  = "TODO_PORT" // имя переменной окружения для переопределения порта
 )
 
+// Имена переменных окружения и значения по умолчанию для таймаутов сервера
+// Значения задаются в формате time.ParseDuration, например 5s или 1m
+const (
+	envReadHeaderTimeoutKey = "TODO_READ_HEADER_TIMEOUT"
+	envReadTimeoutKey       = "TODO_READ_TIMEOUT"
+	envWriteTimeoutKey      = "TODO_WRITE_TIMEOUT"
+	envIdleTimeoutKey       = "TODO_IDLE_TIMEOUT"
+	envShutdownTimeoutKey   = "TODO_SHUTDOWN_TIMEOUT" // время на завершение активных запросов при остановке
+
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultShutdownTimeout   = 10 * time.Second
+)
+
 // resolvePort определяет порт для запуска сервера
 // Сначала проверяет переменную окружения TODO_PORT
 // Если переменная задана и содержит валидный номер порта (1-65535), использует её
@@ -31,39 +53,106 @@ func resolvePort() int {
 	return defaultPort
 }
 
+// resolveDuration читает длительность из переменной окружения key
+// Если переменная не задана или невалидна, возвращает значение по умолчанию def
+func resolveDuration(key string, def time.Duration) time.Duration {
+	if v, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
 // addr формирует строку адреса для сервера в формате ":порт"
 func addr() string { return ":" + strconv.Itoa(resolvePort()) }
 
-// New создает и настраивает HTTP сервер для обслуживания статических файлов
-// Принимает путь к директории с веб-файлами (webDir)
+// New создает и настраивает HTTP сервер для обслуживания API и статических файлов
+// Принимает путь к директории с веб-файлами (webDir), хранилище и часы для обработчиков API
+// Каждый вызов создаёт собственный набор маршрутов, поэтому серверов может быть несколько
 // Возвращает настроенный http.Server
-func New(webDir string) *http.Server {
-	// Инициализируем API обработчики
-	api.Init()
+func New(webDir string, store api.Store, clock api.Clock) *http.Server {
+	mux := http.NewServeMux()
+
+	// Запросы к /api/ обрабатывает API
+	mux.Handle("/api/", api.NewHandler(store, clock))
 
 	// Создаем файловый сервер для обслуживания статических файлов из webDir
 	fileServer := http.FileServer(http.Dir(webDir))
 
 	// Регистрируем обработчик для всех остальных запросов к корню "/"
 	// Все запросы будут направляться к файловому серверу
-	http.Handle("/", fileServer)
+	mux.Handle("/", fileServer)
 
-	// Возвращаем настроенный сервер с адресом и обработчиком логирования
-	return &http.Server{Addr: addr(), Handler: logRequests(http.DefaultServeMux)}
+	// Возвращаем настроенный сервер с адресом, таймаутами и обработчиком логирования
+	return &http.Server{
+		Addr:              addr(),
+		Handler:           logRequests(mux),
+		ReadHeaderTimeout: resolveDuration(envReadHeaderTimeoutKey, defaultReadHeaderTimeout),
+		ReadTimeout:       resolveDuration(envReadTimeoutKey, defaultReadTimeout),
+		WriteTimeout:      resolveDuration(envWriteTimeoutKey, defaultWriteTimeout),
+		IdleTimeout:       resolveDuration(envIdleTimeoutKey, defaultIdleTimeout),
+	}
 }
 
 // Run запускает HTTP сервер для обслуживания файлов из webDir
-// Выводит сообщение о запуске и начинает прослушивание порта
-// Возвращает ошибку, если сервер не может быть запущен
-func Run(webDir string) error {
+// Работает до отмены ctx или получения SIGINT/SIGTERM, после чего
+// дожидается завершения активных запросов (не дольше TODO_SHUTDOWN_TIMEOUT)
+// и закрывает хранилище
+// Возвращает ошибку, если сервер не может быть запущен или корректно остановлен
+func Run(ctx context.Context, webDir string, store *db.Storage) error {
+	// Отменяем контекст при получении сигнала остановки
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Часы можно остановить через TODO_FAKE_NOW, чтобы тесты не зависели от текущей даты
+	clock, err := api.ClockFromEnv()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	if fixed, ok := clock.(api.FixedClock); ok {
+		log.Printf("часы остановлены на %s", fixed.T.Format(time.RFC3339))
+	}
+
 	// Создаем новый сервер
-	s := New(webDir)
+	s := New(webDir, store, clock)
 
 	// Выводим сообщение о том, на каком адресе запущен сервер
 	log.Printf("listening on http://localhost%s", s.Addr)
 
 	// Запускаем сервер и начинаем прослушивание входящих соединений
-	return s.ListenAndServe()
+	errCh := make(chan error, 1)
+	go func() { errCh <- s.ListenAndServe() }()
+
+	select {
+	case err := <-errCh:
+		// Сервер не запустился или остановился сам
+		store.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	// Повторный сигнал во время остановки завершит процесс сразу
+	stop()
+	log.Printf("shutting down")
+
+	grace := resolveDuration(envShutdownTimeoutKey, defaultShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	err = s.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("shutdown: активные запросы не завершились за %s", grace)
+		s.Close()
+	}
+
+	// Закрываем БД только после того, как обработчики перестали к ней обращаться
+	store.Close()
+	if srvErr := <-errCh; srvErr != nil && !errors.Is(srvErr, http.ErrServerClosed) {
+		return srvErr
+	}
+	return err
 }
 
 // logRequests создает middleware для логирования всех HTTP запросов
@@ -9,7 +9,6 @@ import (
 	"net/http/cookiejar"
 	"strconv"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -35,7 +34,7 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
-	if len(Token) > 0 {
+	if token := getToken(); len(token) > 0 {
 		jar, err := cookiejar.New(nil)
 		if err != nil {
 			return nil, err
@@ -43,7 +42,7 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 		jar.SetCookies(req.URL, []*http.Cookie{
 			{
 				Name:  "token",
-				Value: Token,
+				Value: token,
 			},
 		})
 		client.Jar = jar
@@ -106,7 +105,7 @@ func TestAddTask(t *testing.T) {
 			"Ожидается ошибка для задачи %v", v)
 	}
 
-	now := time.Now()
+	now := testNow()
 
 	check := func() {
 		for _, v := range tbl {
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -25,8 +26,44 @@ func getURL(path string) string {
 	return fmt.Sprintf("http://localhost:%d/%s", port, path)
 }
 
+func getToken() string {
+	if envToken := os.Getenv("TODO_TOKEN"); len(envToken) > 0 {
+		return envToken
+	}
+	return Token
+}
+
+// testNow возвращает «сейчас» сервера: момент TODO_FAKE_NOW, если часы остановлены,
+// иначе текущее время. Дата 20060102 означает полдень в часовом поясе TODO_TZ
+func testNow() time.Time {
+	v := os.Getenv("TODO_FAKE_NOW")
+	if v == "" {
+		return time.Now()
+	}
+	loc := time.Local
+	if tz := os.Getenv("TODO_TZ"); tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	if t, err := time.ParseInLocation("20060102", v, loc); err == nil {
+		return t.Add(12 * time.Hour)
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(loc)
+	}
+	return time.Now()
+}
+
 func getBody(path string) ([]byte, error) {
-	resp, err := http.Get(getURL(path))
+	req, err := http.NewRequest(http.MethodGet, getURL(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateBusiness(t *testing.T) {
+	if !FullNextDate {
+		return
+	}
+	tbl := []nextDate{
+		{"20240126", "b", ""},
+		{"20240126", "b 0", ""},
+		{"20240126", "b 401", ""},
+		{"20240126", "b x", ""},
+		{"20240126", "b 1 2", ""},
+		{"20240126", "b 1", "20240129"},
+		{"20240126", "b 5", "20240202"},
+		{"20240120", "b 2", "20240129"},
+		{"20240126", "b 3 until 20240130", ""},
+		{"20240126", "d 5 shift", ""},
+		{"20240126", "y shift x", ""},
+		{"20240126", "y shift", "20250127"},
+		{"20230701", "y shift", "20240701"},
+		{"20230706", "y shift", "20240708"},
+		{"20240126", "y shift until 20250126", ""},
+		{"20240126", "y shift count 2", "20250127"},
+		{"20240126", "b 1 shift", ""},
+		{"20240126", "m 10 shift", "20240212"},
+		{"20240126", "m 1 shift", "20240201"},
+		{"20240126", "mw -1:7 shift", "20240129"},
+		{"20240126", "m 10 shift count 3", "20240212"},
+		{"20240126", "m 10 shift until 20240211", ""},
+	}
+	checkNextDate(t, tbl)
+}
+
+func holidayNextDate(t *testing.T, now, date, repeat string) string {
+	body, err := getBody(fmt.Sprintf("api/nextdate?now=%s&date=%s&repeat=%s",
+		now, date, url.QueryEscape(repeat)))
+	assert.NoError(t, err)
+	return strings.TrimSpace(string(body))
+}
+
+func TestHolidays(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+	defer db.Exec(`DELETE FROM holidays WHERE date LIKE '2031%'`)
+
+	countHolidays := func() int {
+		var n int
+		assert.NoError(t, db.Get(&n, `SELECT COUNT(*) FROM holidays`))
+		return n
+	}
+
+	assert.Equal(t, "20310101", holidayNextDate(t, "20301231", "20301231", "b 1"))
+
+	// Файл с ошибкой не загружается целиком
+	before := countHolidays()
+	code, m := postFile(t, "api/holidays", "text/csv",
+		"date,name\n20310101,Новый год\n31.02.2031,Ошибка\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.NotEmpty(t, m["error"])
+	assert.Equal(t, before, countHolidays())
+
+	code, m = postFile(t, "api/holidays?format=csv", "text/csv",
+		"date,name\n20310101,Новый год\n02.01.2031,Каникулы\n")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), m["loaded"])
+
+	code, m = postFile(t, "api/holidays?format=ics", "text/calendar", strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Перенос",
+		"DTSTART;VALUE=DATE:20310106",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(1), m["loaded"])
+
+	body, err := getBody("api/holidays")
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `{"date":"20310102","name":"Каникулы"}`)
+
+	assert.Equal(t, "20310103", holidayNextDate(t, "20301231", "20301231", "b 1"))
+	assert.Equal(t, "20310108", holidayNextDate(t, "20301231", "20301231", "b 3"))
+	assert.Equal(t, "20310103", holidayNextDate(t, "20301231", "20301215", "m 1 shift"))
+	assert.Equal(t, "20310103", holidayNextDate(t, "20301231", "20300102", "y shift"))
+}
+
+func TestShiftNoDrift(t *testing.T) {
+	// Перенос на рабочий день не должен сдвигать следующие повторения
+	body, err := getBody("api/occurrences?now=20211201&date=20210101&count=5&repeat=" + url.QueryEscape("y shift"))
+	assert.NoError(t, err)
+	var resp occurrencesResp
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	assert.Empty(t, resp.Error)
+	assert.Equal(t, []string{"20220103", "20230102", "20240101", "20250101", "20260101"}, resp.Dates)
+
+	db := openDB(t)
+	defer db.Close()
+
+	for _, v := range []struct {
+		date   string
+		repeat string
+		want   []string
+	}{
+		{"20320101", "y shift", []string{"20330103", "20340102", "20350101", "20360101"}},
+		// 1 мая 2032 года — суббота, перенос на понедельник 3 мая не должен повторяться в июне
+		{"20320403", "m 1,3 shift", []string{"20320503", "20320601", "20320603"}},
+		{"20320405", "mw 1:6,1:1 shift", []string{"20320503", "20320607", "20320705"}},
+	} {
+		id := addTask(t, task{date: v.date, title: "Перенос " + v.repeat, repeat: v.repeat})
+		for _, want := range v.want {
+			ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+			assert.NoError(t, err)
+			assert.Empty(t, ret)
+
+			var task Task
+			assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+			assert.Equal(t, want, task.Date, v.repeat)
+		}
+		_, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"final_project/pkg/ical"
+)
+
+func TestCalendar(t *testing.T) {
+	feedToken := os.Getenv("TODO_CALENDAR_TOKEN")
+	if len(feedToken) == 0 && len(os.Getenv("TODO_PASSWORD")) > 0 {
+		t.Skip("TODO_CALENDAR_TOKEN не задан, лента календаря отключена")
+	}
+
+	id := addTask(t, task{
+		title:   "Планёрка, отдел; продаж",
+		comment: "Переговорная №2",
+		repeat:  "w 1,3,5",
+	})
+
+	body, err := getBody("api/calendar.ics?token=" + url.QueryEscape(feedToken))
+	assert.NoError(t, err)
+	ics := string(body)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+
+	uid := "UID:task-" + id + "@final_project\r\n"
+	assert.Contains(t, ics, uid)
+	event := ics[strings.Index(ics, uid):]
+	event = event[:strings.Index(event, "END:VEVENT")]
+	assert.Contains(t, event, `SUMMARY:Планёрка\, отдел\; продаж`+"\r\n")
+	assert.Contains(t, event, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR\r\n")
+}
+
+func postICS(t *testing.T, apipath, ics string) map[string]any {
+	return postICSInTimezone(t, apipath, "", ics)
+}
+
+// postICSInTimezone загружает календарь с заголовком X-Timezone (если tz не пустой)
+func postICSInTimezone(t *testing.T, apipath, tz, ics string) map[string]any {
+	req, err := http.NewRequest(http.MethodPost, getURL(apipath), strings.NewReader(ics))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "text/calendar")
+	if tz != "" {
+		req.Header.Set("X-Timezone", tz)
+	}
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return m
+}
+
+func TestImportICS(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"UID:import-1",
+		`SUMMARY:Импорт\, встреча`,
+		"DESCRIPTION:Из календаря",
+		"DTSTART;VALUE=DATE:20240105",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,FR",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:import-2",
+		"SUMMARY:Каждый час",
+		"DTSTART:20240105T100000Z",
+		"RRULE:FREQ=HOURLY",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	m := postICS(t, "api/import/ics?dry_run=true", ics)
+	assert.Equal(t, true, m["dry_run"])
+	assert.Equal(t, float64(0), m["created"])
+	assert.Equal(t, float64(1), m["errors"])
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	m = postICS(t, "api/import/ics", ics)
+	assert.Equal(t, float64(1), m["created"])
+	events, _ := m["events"].([]any)
+	assert.Equal(t, 2, len(events))
+	if len(events) == 2 {
+		ev := events[0].(map[string]any)
+		assert.Equal(t, "created", ev["status"])
+		tsk := ev["task"].(map[string]any)
+
+		var task Task
+		err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, tsk["id"])
+		assert.NoError(t, err)
+		assert.Equal(t, "Импорт, встреча", task.Title)
+		assert.Equal(t, "Из календаря", task.Comment)
+		assert.Equal(t, "w 1,5", task.Repeat)
+
+		ev = events[1].(map[string]any)
+		assert.Equal(t, "error", ev["status"])
+		assert.NotEmpty(t, ev["error"])
+	}
+}
+
+func TestImportICSTimezone(t *testing.T) {
+	// 22:00 UTC 5 января — уже 6 января в Токио и ещё 5 января в Нью-Йорке
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Созвон с Токио",
+		"DTSTART:20400105T220000Z",
+		"RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20400110T220000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	for _, v := range []struct {
+		tz     string
+		date   string
+		repeat string
+	}{
+		{"Asia/Tokyo", "20400106", "d 2 until 20400111"},
+		{"America/New_York", "20400105", "d 2 until 20400110"},
+	} {
+		m := postICSInTimezone(t, "api/import/ics", v.tz, ics)
+		assert.Equal(t, float64(1), m["created"], v.tz)
+		events, _ := m["events"].([]any)
+		if assert.Len(t, events, 1) {
+			tsk := events[0].(map[string]any)["task"].(map[string]any)
+			id := fmt.Sprint(tsk["id"])
+			_, _, task := matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+			assert.Equal(t, v.date, task["date"], v.tz)
+			assert.Equal(t, v.repeat, task["repeat"], v.tz)
+			status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+			assert.Equal(t, http.StatusOK, status)
+		}
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	// Длинное описание переносится на несколько строк и должно читаться обратно целиком
+	desc := strings.Repeat("Подготовить отчёт; сверить цифры, отправить. ", 8)
+	event := ical.Event{
+		UID:         "task-1@final_project",
+		Date:        time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC),
+		Summary:     "Квартальный отчёт",
+		Description: desc,
+		RRule:       "FREQ=MONTHLY;BYMONTHDAY=15",
+	}
+
+	var buf bytes.Buffer
+	w := ical.NewWriter(&buf, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, w.Begin("Задачи"))
+	assert.NoError(t, w.WriteEvent(event))
+	assert.NoError(t, w.End())
+	assert.Greater(t, strings.Count(buf.String(), "\r\n "), 3)
+
+	list, err := ical.Parse(&buf, time.UTC)
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) {
+		c := list[0]
+		assert.NoError(t, c.Err)
+		assert.Equal(t, event.UID, c.UID)
+		assert.Equal(t, event.Summary, c.Summary)
+		assert.Equal(t, desc, c.Description)
+		assert.Equal(t, "20300115", c.Date)
+		assert.Equal(t, event.RRule, c.RRule)
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFakeNow проверяет, что при TODO_FAKE_NOW сервер считает «сегодня» по остановленным часам.
+// Без переменной тест пропускается: сервер работает по системным часам
+func TestFakeNow(t *testing.T) {
+	if os.Getenv("TODO_FAKE_NOW") == "" {
+		t.Skip("TODO_FAKE_NOW не задана")
+	}
+	db := openDB(t)
+	defer db.Close()
+
+	today := testNow().Format(`20060102`)
+	tomorrow := testNow().AddDate(0, 0, 1).Format(`20060102`)
+
+	body, err := getBody("api/nextdate?date=20000101&repeat=d+1")
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, string(body))
+
+	id := addTask(t, task{title: "Задача на сегодня"})
+	var got Task
+	err = db.Get(&got, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, today, got.Date)
+
+	id = addTask(t, task{
+		date:   "20000101",
+		title:  "Ежедневная задача",
+		repeat: "d 1",
+	})
+	err = db.Get(&got, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, tomorrow, got.Date)
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&got, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, testNow().AddDate(0, 0, 2).Format(`20060102`), got.Date)
+}
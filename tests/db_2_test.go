@@ -3,7 +3,6 @@ package tests
 import (
 	"os"
 	"testing"
-	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -11,11 +10,18 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64  `db:"id"`
+	Date      string `db:"date"`
+	Title     string `db:"title"`
+	Comment   string `db:"comment"`
+	Repeat    string `db:"repeat"`
+	Remaining int    `db:"remaining"`
+	Time      string `db:"time"`
+	Duration  int    `db:"duration"`
+	Version   int    `db:"version"`
+	Priority  int    `db:"priority"`
+	Created   string `db:"created_at"`
+	Anchor    string `db:"anchor"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -41,7 +47,7 @@ func TestDB(t *testing.T) {
 	before, err := count(db)
 	assert.NoError(t, err)
 
-	today := time.Now().Format(`20060102`)
+	today := testNow().Format(`20060102`)
 
 	res, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) 
 	VALUES (?, 'Todo', 'Комментарий', '')`, today)
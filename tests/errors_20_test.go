@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// errorResp выполняет запрос и разбирает ответ с ошибкой
+func errorResp(t *testing.T, method, apipath string, values map[string]any) (int, map[string]any) {
+	var body string
+	if values != nil {
+		data, err := json.Marshal(values)
+		assert.NoError(t, err)
+		body = string(data)
+	}
+	req, err := http.NewRequest(method, getURL(apipath), strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Contains(t, resp.Header.Get("Content-Type"), "application/json", apipath)
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m), apipath)
+	return resp.StatusCode, m
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	tbl := []struct {
+		method string
+		path   string
+		values map[string]any
+		status int
+		code   string
+		field  string
+	}{
+		{http.MethodGet, "api/task?id=999999999", nil, http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "api/task", nil, http.StatusBadRequest, "validation", "id"},
+		{http.MethodPost, "api/task", map[string]any{"title": ""}, http.StatusBadRequest, "validation", "title"},
+		{http.MethodPost, "api/task", map[string]any{"title": "Задача", "date": "26.01.2024"},
+			http.StatusBadRequest, "validation", "date"},
+		{http.MethodPost, "api/task", map[string]any{"title": "Задача", "repeat": "k 34"},
+			http.StatusBadRequest, "validation", "repeat"},
+		{http.MethodPost, "api/task", map[string]any{"title": "Задача", "time": "25:00"},
+			http.StatusBadRequest, "validation", "time"},
+		{http.MethodPut, "api/task", map[string]any{"id": "999999999", "title": "Задача"},
+			http.StatusNotFound, "not_found", ""},
+		{http.MethodPatch, "api/task", nil, http.StatusMethodNotAllowed, "method_not_allowed", ""},
+		{http.MethodGet, "api/tasks?limit=0", nil, http.StatusBadRequest, "validation", "limit"},
+		{http.MethodGet, "api/nextdate?repeat=d+1", nil, http.StatusBadRequest, "validation", "date"},
+		{http.MethodGet, "api/nextdate?now=20240126&date=20240126&repeat=" + url.QueryEscape("k 34"), nil,
+			http.StatusBadRequest, "validation", "repeat"},
+		{http.MethodGet, "api/nextdate?now=yesterday&date=20240126&repeat=d+1", nil,
+			http.StatusBadRequest, "validation", "now"},
+		{http.MethodPost, "api/task/undo?token=unknown", nil, http.StatusNotFound, "not_found", ""},
+		{http.MethodGet, "api/unknown", nil, http.StatusNotFound, "not_found", ""},
+	}
+	for _, v := range tbl {
+		status, m := errorResp(t, v.method, v.path, v.values)
+		assert.Equal(t, v.status, status, "%s %s", v.method, v.path)
+		assert.Equal(t, v.code, m["code"], "%s %s", v.method, v.path)
+		assert.NotEmpty(t, m["error"], "%s %s", v.method, v.path)
+		if v.field == "" {
+			assert.Nil(t, m["field"], "%s %s", v.method, v.path)
+		} else {
+			assert.Equal(t, v.field, m["field"], "%s %s", v.method, v.path)
+		}
+	}
+}
+
+func TestErrorConflict(t *testing.T) {
+	// Повторяющийся id при замене всех задач — конфликт; транзакция откатывается, задачи не удаляются
+	id := addTask(t, task{date: "20240201", title: "Задача до импорта"})
+
+	data := `[{"id":"777001","title":"Первая"},{"id":"777001","title":"Вторая"}]`
+	status, m := postFile(t, "api/import?format=json&mode=replace", "application/json", data)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "conflict", m["code"])
+	assert.NotEmpty(t, m["error"])
+
+	status, _ = errorResp(t, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, status)
+	notFoundTask(t, "777001")
+}
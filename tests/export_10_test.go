@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func postFile(t *testing.T, apipath, contentType, data string) (int, map[string]any) {
+	req, err := http.NewRequest(http.MethodPost, getURL(apipath), strings.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return resp.StatusCode, m
+}
+
+func TestExportImport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		title:   "Экспорт, \"CSV\"",
+		comment: "многострочный\nкомментарий",
+		repeat:  "d 5",
+	})
+
+	body, err := getBody("api/export?format=json")
+	assert.NoError(t, err)
+	var exported struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &exported))
+	total, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, total, len(exported.Tasks))
+
+	body, err = getBody("api/export?format=csv")
+	assert.NoError(t, err)
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, total+1, len(records))
+	assert.Equal(t, []string{"id", "date", "title", "comment", "repeat", "time", "duration", "priority", "created",
+		"tags", "remaining"}, records[0])
+
+	// Строка с ошибкой отменяет весь импорт
+	csvData := "title,date,repeat\nПервая,,\nВторая,20240192,\n"
+	status, m := postFile(t, "api/import?format=csv", "text/csv", csvData)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, float64(1), m["errors"])
+	after, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, total, after)
+
+	// В режиме upsert задача с существующим id обновляется
+	csvData = "id,title,comment,repeat\n" + id + ",Обновлено,,d 5\n,Новая задача,,\n"
+	status, m = postFile(t, "api/import?format=csv&mode=upsert", "text/csv", csvData)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(2), m["imported"])
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Обновлено", task.Title)
+	after, err = count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, total+1, after)
+
+	// Приоритет, момент создания и метки берутся из файла, без момента создания задача создана сейчас
+	csvData = "title,priority,created,tags\nСтарая задача,2,2021-03-04T05:06:07+03:00,\"Work10, home10\"\n" +
+		"Без даты создания,,,\n"
+	status, m = postFile(t, "api/import?format=csv", "text/csv", csvData)
+	assert.Equal(t, http.StatusOK, status)
+	rows, _ := m["rows"].([]any)
+	if assert.Len(t, rows, 2) {
+		oldID := rows[0].(map[string]any)["id"]
+		assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, oldID))
+		assert.Equal(t, 2, task.Priority)
+		assert.Equal(t, "2021-03-04T02:06:07Z", task.Created)
+		_, _, m = matchRequest(t, http.MethodGet, fmt.Sprint("api/task?id=", oldID), "", nil)
+		assert.Equal(t, []any{"home10", "work10"}, m["tags"])
+		body, err = getBody("api/export?format=csv")
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "2021-03-04T02:06:07Z,\"home10,work10\",\n")
+		newID := rows[1].(map[string]any)["id"]
+		assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, newID))
+		assert.Equal(t, 0, task.Priority)
+		assert.Greater(t, task.Created, "2021-03-04T02:06:07Z")
+		for _, id := range []any{oldID, newID} {
+			_, err := db.Exec(`DELETE FROM scheduler WHERE id=?`, id)
+			assert.NoError(t, err)
+		}
+	}
+	status, m = postFile(t, "api/import?format=csv", "text/csv", "title,created\nЗадача,вчера\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, float64(1), m["errors"])
+
+	// В режиме replace остаются только импортированные задачи с их id
+	data, err := json.Marshal(exported)
+	assert.NoError(t, err)
+	status, m = postFile(t, "api/import?format=json&mode=replace", "application/json", string(data))
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, float64(total), m["imported"])
+	after, err = count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, total, after)
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Экспорт, \"CSV\"", task.Title)
+	assert.Equal(t, "многострочный\nкомментарий", task.Comment)
+	for _, v := range exported.Tasks {
+		if v["id"] == id {
+			assert.NotEmpty(t, task.Created)
+			assert.Equal(t, v["created"], task.Created)
+		}
+	}
+}
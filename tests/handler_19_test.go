@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"final_project/pkg/api"
+	"final_project/pkg/server"
+)
+
+// memNow — момент, на котором остановлены часы обработчиков с хранилищем в памяти
+var memNow = time.Date(2024, 1, 26, 12, 0, 0, 0, time.UTC)
+
+// newMemServer запускает обработчик API с хранилищем в памяти и остановленными часами
+func newMemServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(api.NewHandler(newMemStore(), api.FixedClock{T: memNow}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// memRequest выполняет запрос к серверу srv и возвращает код ответа и разобранное тело
+func memRequest(t *testing.T, srv *httptest.Server, method, apipath string, values map[string]any) (int, map[string]any) {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, srv.URL+"/"+apipath, bytes.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var m map[string]any
+	if len(bytes.TrimSpace(body)) > 0 {
+		assert.NoError(t, json.Unmarshal(body, &m), string(body))
+	}
+	return resp.StatusCode, m
+}
+
+func TestMemStoreHandler(t *testing.T) {
+	srv := newMemServer(t)
+
+	code, ret := memRequest(t, srv, http.MethodPost, "api/task", map[string]any{
+		"title":  "Полить цветы",
+		"repeat": "d 3",
+	})
+	assert.Equal(t, http.StatusCreated, code)
+	id := fmt.Sprint(ret["id"])
+	assert.Equal(t, "1", id)
+
+	// Дата по умолчанию берётся с остановленных часов
+	code, ret = memRequest(t, srv, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "20240126", ret["date"])
+
+	code, _ = memRequest(t, srv, http.MethodPost, "api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	_, ret = memRequest(t, srv, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, "20240129", ret["date"])
+
+	code, ret = memRequest(t, srv, http.MethodPut, "api/task", map[string]any{
+		"id":    id,
+		"date":  "20240201",
+		"title": "Полить цветы на балконе",
+	})
+	assert.Equal(t, http.StatusOK, code, ret)
+
+	_, ret = memRequest(t, srv, http.MethodGet, "api/tasks", nil)
+	tasks, _ := ret["tasks"].([]any)
+	if assert.Len(t, tasks, 1) {
+		task := tasks[0].(map[string]any)
+		assert.Equal(t, "Полить цветы на балконе", task["title"])
+		assert.Equal(t, "20240201", task["date"])
+	}
+
+	code, _ = memRequest(t, srv, http.MethodDelete, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, ret = memRequest(t, srv, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.NotEmpty(t, ret["error"])
+}
+
+func TestMemStoreIndependent(t *testing.T) {
+	first := newMemServer(t)
+	second := newMemServer(t)
+
+	_, ret := memRequest(t, first, http.MethodPost, "api/task", map[string]any{
+		"date":  "20240201",
+		"title": "Только в первом экземпляре",
+	})
+	id := fmt.Sprint(ret["id"])
+
+	code, _ := memRequest(t, first, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = memRequest(t, second, http.MethodGet, "api/task?id="+id, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestServerNewTwice(t *testing.T) {
+	clock := api.FixedClock{T: memNow}
+
+	// Каждый сервер регистрирует маршруты в собственном mux, поэтому повторный вызов не паникует
+	for i := 0; i < 2; i++ {
+		s := server.New("../web", newMemStore(), clock)
+		srv := httptest.NewServer(s.Handler)
+		code, ret := memRequest(t, srv, http.MethodGet, "api/tasks", nil)
+		srv.Close()
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, ret, "tasks")
+	}
+}
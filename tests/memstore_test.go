@@ -0,0 +1,448 @@
+package tests
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"final_project/pkg/db"
+	"final_project/pkg/nextdate"
+)
+
+// memStore — хранилище задач в памяти для тестов обработчиков без SQLite
+// Поиск упрощён: текст ищется как подстрока без учёта регистра, без ранжирования и сниппетов
+type memStore struct {
+	mu          sync.Mutex
+	tasks       map[int64]db.Task
+	nextID      int64
+	completions []db.Completion
+	undo        map[string]memUndo
+	holidays    map[string]string
+}
+
+// memUndo — снимок задачи для отмены операции
+type memUndo struct {
+	task         db.Task
+	after        int
+	completionID int64
+	expires      time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		tasks:    map[int64]db.Task{},
+		undo:     map[string]memUndo{},
+		holidays: map[string]string{},
+	}
+}
+
+// copyTask возвращает копию задачи, чтобы вызывающий не менял данные хранилища
+func copyTask(task db.Task) *db.Task {
+	task.Tags = append([]string(nil), task.Tags...)
+	return &task
+}
+
+// insert сохраняет задачу с указанным id (0 — новый id), версия задачи увеличивается
+func (m *memStore) insert(task *db.Task, id int64) int64 {
+	if id == 0 {
+		m.nextID++
+		id = m.nextID
+	} else if id > m.nextID {
+		m.nextID = id
+	}
+	t := *copyTask(*task)
+	t.ID = strconv.FormatInt(id, 10)
+	t.Version = m.tasks[id].Version + 1
+	t.RepeatText = ""
+	t.Snippet = ""
+	m.tasks[id] = t
+	return id
+}
+
+// sorted возвращает задачи в порядке даты, времени и id
+func (m *memStore) sorted() []db.Task {
+	list := make([]db.Task, 0, len(m.tasks))
+	for _, t := range m.tasks {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return memLess(list[i], list[j], db.SortDate) })
+	return list
+}
+
+func memID(id string) int64 {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+func (m *memStore) AddTask(task *db.Task) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.insert(task, 0), nil
+}
+
+func (m *memStore) Tasks(filter db.TaskFilter) (*db.TasksPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matched []db.Task
+	for _, t := range m.sorted() {
+		if filter.Search != "" {
+			if d, err := time.Parse("02.01.2006", filter.Search); err == nil {
+				if t.Date != d.Format("20060102") {
+					continue
+				}
+			} else {
+				text := strings.ToLower(t.Title + " " + t.Comment)
+				if !strings.Contains(text, strings.ToLower(filter.Search)) {
+					continue
+				}
+			}
+		}
+		if filter.From != "" && t.Date < filter.From || filter.To != "" && t.Date > filter.To {
+			continue
+		}
+		if filter.Repeat == db.RepeatYes && t.Repeat == "" || filter.Repeat == db.RepeatNo && t.Repeat != "" {
+			continue
+		}
+		if filter.Overdue != "" && t.Date >= filter.Overdue {
+			continue
+		}
+		if len(filter.Tags) > 0 && !memHasTags(t.Tags, filter.Tags, filter.TagMode) {
+			continue
+		}
+		matched = append(matched, t)
+	}
+
+	page := &db.TasksPage{Tasks: []*db.Task{}, Total: len(matched)}
+	sortKey := filter.Sort
+	if sortKey == "" {
+		sortKey = db.SortDate
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return memLess(matched[i], matched[j], sortKey) })
+
+	// Позиция курсора — задача с ключом сортировки, датой, временем и id из курсора
+	c := filter.Cursor
+	after := db.Task{Title: c.Key, Created: c.Key, Date: c.Date, Time: c.Time, ID: strconv.FormatInt(c.ID, 10)}
+	after.Priority, _ = strconv.Atoi(c.Key)
+	for _, t := range matched {
+		if c.Date != "" && c.Sort == sortKey && !memLess(after, t, sortKey) {
+			continue
+		}
+		if len(page.Tasks) == filter.Limit {
+			last := page.Tasks[len(page.Tasks)-1]
+			page.Next = &db.Cursor{Sort: sortKey, Date: last.Date, Time: last.Time, ID: memID(last.ID)}
+			switch sortKey {
+			case db.SortPriority:
+				page.Next.Key = strconv.Itoa(last.Priority)
+			case db.SortTitle:
+				page.Next.Key = last.Title
+			case db.SortCreated:
+				page.Next.Key = last.Created
+			}
+			break
+		}
+		page.Tasks = append(page.Tasks, copyTask(t))
+	}
+	return page, nil
+}
+
+// memCount возвращает число повторений count правила repeat
+func memCount(repeat string) int {
+	if rule, err := nextdate.Parse(repeat); err == nil && rule != nil {
+		return rule.Count
+	}
+	return 0
+}
+
+// memLess сравнивает задачи по ключу сортировки sortKey, затем по дате, времени и id
+func memLess(a, b db.Task, sortKey string) bool {
+	switch {
+	case sortKey == db.SortPriority && a.Priority != b.Priority:
+		return a.Priority > b.Priority
+	case sortKey == db.SortTitle && !strings.EqualFold(a.Title, b.Title):
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	case sortKey == db.SortCreated && a.Created != b.Created:
+		return a.Created > b.Created
+	case a.Date != b.Date:
+		return a.Date < b.Date
+	case a.Time != b.Time:
+		return a.Time < b.Time
+	}
+	return memID(a.ID) < memID(b.ID)
+}
+
+// memHasTags сообщает, отмечена ли задача с метками have метками want в режиме mode
+func memHasTags(have, want []string, mode string) bool {
+	found := 0
+	for _, tag := range want {
+		for _, h := range have {
+			if h == tag {
+				found++
+				break
+			}
+		}
+	}
+	if mode == db.TagModeOr {
+		return found > 0
+	}
+	return found == len(want)
+}
+
+// check проверяет, что задача есть и её версия совпадает с version (0 — без проверки)
+func (m *memStore) check(id string, version int) (db.Task, error) {
+	t, ok := m.tasks[memID(id)]
+	if !ok {
+		return t, db.NotFound("задача не найдена")
+	}
+	if version != 0 && version != t.Version {
+		return t, db.ErrVersionMismatch
+	}
+	return t, nil
+}
+
+func (m *memStore) GetTask(id string) (*db.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tasks[memID(id)]
+	if !ok {
+		return nil, db.NotFound("задача не найдена")
+	}
+	return copyTask(t), nil
+}
+
+func (m *memStore) UpdateTask(task *db.Task, keep db.Fields) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, err := m.check(task.ID, task.Version)
+	if err != nil {
+		return err
+	}
+	t := *task
+	if memCount(t.Repeat) == memCount(old.Repeat) {
+		t.Remaining = old.Remaining
+	}
+	t.Created = old.Created
+	if t.Anchor == "" && t.Date == old.Date && t.Repeat == old.Repeat {
+		t.Anchor = old.Anchor
+	}
+	if keep&db.FieldTags != 0 {
+		t.Tags = old.Tags
+	}
+	if keep&db.FieldPriority != 0 {
+		t.Priority = old.Priority
+	}
+	if keep&db.FieldTime != 0 {
+		t.Time, t.Duration = old.Time, old.Duration
+	}
+	m.insert(&t, memID(task.ID))
+	task.Version = m.tasks[memID(task.ID)].Version
+	return nil
+}
+
+func (m *memStore) DeleteTask(id string, version int, undo *db.UndoRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.check(id, version)
+	if err != nil {
+		return err
+	}
+	delete(m.tasks, memID(id))
+	m.saveUndo(undo, t, 0, 0)
+	return nil
+}
+
+func (m *memStore) CompleteTask(id string, version int, now time.Time, undo *db.UndoRecord) (*db.Done, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.check(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	done := &db.Done{Task: copyTask(t)}
+	var anchor string
+	if t.Repeat != "" && t.Remaining != 1 {
+		holidays := nextdate.Holidays{}
+		for date := range m.holidays {
+			holidays[date] = true
+		}
+		rule, err := nextdate.Parse(t.Repeat)
+		if err == nil {
+			done.NextDate, anchor, err = rule.NextShifted(now, t.Date, t.Anchor, holidays)
+		}
+		if err != nil && !errors.Is(err, nextdate.ErrEnded) {
+			return nil, db.Invalid("repeat", "%v", err)
+		}
+	}
+
+	if done.NextDate == "" {
+		delete(m.tasks, memID(id))
+	} else {
+		t.Date, t.Anchor = done.NextDate, anchor
+		if t.Remaining > 0 {
+			t.Remaining--
+		}
+		m.insert(&t, memID(id))
+	}
+
+	done.CompletionID = int64(len(m.completions) + 1)
+	m.completions = append(m.completions, db.Completion{
+		ID:       strconv.FormatInt(done.CompletionID, 10),
+		TaskID:   t.ID,
+		Title:    t.Title,
+		Date:     done.Task.Date,
+		DoneAt:   now.UTC().Format(db.DoneAtFormat),
+		NextDate: done.NextDate,
+	})
+	if done.NextDate != "" {
+		done.Version = m.tasks[memID(id)].Version
+	}
+	m.saveUndo(undo, *done.Task, done.Version, done.CompletionID)
+	return done, nil
+}
+
+func (m *memStore) AddTasks(tasks []*db.Task) ([]int64, error) {
+	return m.ImportTasks(tasks, db.ImportAppend)
+}
+
+func (m *memStore) ImportTasks(tasks []*db.Task, mode string) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mode == db.ImportReplace {
+		m.tasks = map[int64]db.Task{}
+	}
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		var id int64
+		if mode != db.ImportAppend {
+			id = memID(task.ID)
+		}
+		ids = append(ids, m.insert(task, id))
+	}
+	return ids, nil
+}
+
+func (m *memStore) EachTask(fn func(*db.Task) error) error {
+	m.mu.Lock()
+	list := m.sorted()
+	m.mu.Unlock()
+	for _, t := range list {
+		if err := fn(copyTask(t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memStore) Tags() ([]*db.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := map[string]int{}
+	for _, t := range m.tasks {
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+	list := []*db.Tag{}
+	for name, count := range counts {
+		list = append(list, &db.Tag{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+func (m *memStore) TaskHistory(taskID string) ([]*db.Completion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []*db.Completion{}
+	for i := len(m.completions) - 1; i >= 0; i-- {
+		if c := m.completions[i]; c.ID != "" && c.TaskID == taskID {
+			list = append(list, &c)
+		}
+	}
+	return list, nil
+}
+
+func (m *memStore) Completions(from, to time.Time) ([]*db.Completion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []*db.Completion{}
+	for _, c := range m.completions {
+		if c.ID == "" {
+			continue
+		}
+		if !from.IsZero() && c.DoneAt < from.UTC().Format(db.DoneAtFormat) ||
+			!to.IsZero() && c.DoneAt >= to.UTC().Format(db.DoneAtFormat) {
+			continue
+		}
+		list = append(list, &c)
+	}
+	return list, nil
+}
+
+// saveUndo сохраняет снимок задачи, если операция вызвана с undo
+func (m *memStore) saveUndo(undo *db.UndoRecord, task db.Task, after int, completionID int64) {
+	if undo != nil {
+		m.undo[undo.Token] = memUndo{task: task, after: after, completionID: completionID, expires: undo.Expires}
+	}
+}
+
+func (m *memStore) Undo(token string, now time.Time) (*db.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.undo[token]
+	if !ok || now.After(u.expires) {
+		return nil, db.NotFound("операция для отмены не найдена")
+	}
+	if cur, ok := m.tasks[memID(u.task.ID)]; ok && cur.Version != u.after {
+		return nil, db.ErrVersionMismatch
+	}
+	delete(m.undo, token)
+	if _, ok := m.tasks[memID(u.task.ID)]; !ok {
+		// Удалённая задача восстанавливается со следующей версией после снимка
+		m.tasks[memID(u.task.ID)] = db.Task{Version: u.task.Version}
+	}
+	m.insert(&u.task, memID(u.task.ID))
+	u.task.Version = m.tasks[memID(u.task.ID)].Version
+	if u.completionID != 0 {
+		// Удалённая запись истории остаётся в срезе с пустым ID, чтобы не сдвигать номера
+		m.completions[u.completionID-1].ID = ""
+	}
+	return copyTask(u.task), nil
+}
+
+func (m *memStore) Holidays() ([]*db.Holiday, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []*db.Holiday{}
+	for date, name := range m.holidays {
+		list = append(list, &db.Holiday{Date: date, Name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	return list, nil
+}
+
+func (m *memStore) HolidayDates() (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dates := make(map[string]bool, len(m.holidays))
+	for date := range m.holidays {
+		dates[date] = true
+	}
+	return dates, nil
+}
+
+func (m *memStore) SaveHolidays(list []*db.Holiday, replace bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if replace {
+		m.holidays = map[string]string{}
+	}
+	for _, h := range list {
+		m.holidays[h.Date] = h.Name
+	}
+	return nil
+}
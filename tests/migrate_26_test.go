@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"final_project/pkg/db"
+)
+
+// baselineDB копирует scheduler.db из репозитория — БД в схеме до миграций с тестовыми задачами —
+// во временный каталог и открывает копию
+func baselineDB(t *testing.T) *sqlx.DB {
+	data, err := os.ReadFile("../scheduler.db")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	file := filepath.Join(t.TempDir(), "scheduler.db")
+	assert.NoError(t, os.WriteFile(file, data, 0o644))
+
+	conn, err := sqlx.Connect("sqlite", file)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return conn
+}
+
+// schemaDump возвращает описание всех объектов схемы БД
+func schemaDump(t *testing.T, conn *sqlx.DB) []string {
+	var list []string
+	assert.NoError(t, conn.Select(&list,
+		`SELECT type || ' ' || name || ' ' || ifnull(sql, '') FROM sqlite_master ORDER BY type, name`))
+	return list
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	conn := baselineDB(t)
+	defer conn.Close()
+
+	var version int
+	assert.NoError(t, conn.Get(&version, `PRAGMA user_version`))
+	assert.Equal(t, 0, version)
+	var tasks []Task
+	assert.NoError(t, conn.Select(&tasks, `SELECT id, date, title, comment, repeat FROM scheduler ORDER BY id`))
+	assert.NotEmpty(t, tasks)
+
+	applied, err := db.Migrate(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, len(db.Migrations), applied)
+	assert.NoError(t, conn.Get(&version, `PRAGMA user_version`))
+	assert.Equal(t, db.LatestVersion(), version)
+
+	// Задачи сохраняются, новые колонки получают значения по умолчанию
+	var migrated []Task
+	assert.NoError(t, conn.Select(&migrated, `SELECT * FROM scheduler ORDER BY id`))
+	if assert.Len(t, migrated, len(tasks)) {
+		for i, task := range migrated {
+			assert.Equal(t, tasks[i].ID, task.ID)
+			assert.Equal(t, tasks[i].Date, task.Date)
+			assert.Equal(t, tasks[i].Title, task.Title)
+			assert.Equal(t, tasks[i].Comment, task.Comment)
+			assert.Equal(t, tasks[i].Repeat, task.Repeat)
+			assert.Equal(t, 1, task.Version)
+			assert.Equal(t, 0, task.Priority)
+		}
+
+		// Полнотекстовый индекс построен по задачам, которые были до миграции
+		var found int64
+		assert.NoError(t, conn.Get(&found, `SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ? LIMIT 1`,
+			`"`+migrated[0].Title+`"`))
+		assert.Equal(t, migrated[0].ID, found)
+	}
+
+	status, err := db.Status(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, db.LatestVersion(), status.Current)
+	assert.Empty(t, status.Pending)
+}
+
+func TestMigrateTwice(t *testing.T) {
+	conn := baselineDB(t)
+	defer conn.Close()
+
+	_, err := db.Migrate(conn)
+	assert.NoError(t, err)
+	schema := schemaDump(t, conn)
+	var total int
+	assert.NoError(t, conn.Get(&total, `SELECT count(*) FROM scheduler`))
+
+	// Повторный запуск ничего не применяет и схему не меняет
+	applied, err := db.Migrate(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, applied)
+	assert.Equal(t, schema, schemaDump(t, conn))
+
+	var version, after int
+	assert.NoError(t, conn.Get(&version, `PRAGMA user_version`))
+	assert.Equal(t, db.LatestVersion(), version)
+	assert.NoError(t, conn.Get(&after, `SELECT count(*) FROM scheduler`))
+	assert.Equal(t, total, after)
+
+	// БД от более новой версии программы не трогается
+	_, err = conn.Exec(`PRAGMA user_version = 1000`)
+	assert.NoError(t, err)
+	_, err = db.Migrate(conn)
+	assert.Error(t, err)
+}
@@ -37,22 +37,7 @@ func TestNextDate(t *testing.T) {
 		{"20231225", "d 12", `20240130`},
 		{"20240228", "d 1", "20240229"},
 	}
-	check := func() {
-		for _, v := range tbl {
-			urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
-				url.QueryEscape(v.date), url.QueryEscape(v.repeat))
-			get, err := getBody(urlPath)
-			assert.NoError(t, err)
-			next := strings.TrimSpace(string(get))
-			_, err = time.Parse("20060102", next)
-			if err != nil && len(v.want) == 0 {
-				continue
-			}
-			assert.Equal(t, v.want, next, `{%q, %q, %q}`,
-				v.date, v.repeat, v.want)
-		}
-	}
-	check()
+	checkNextDate(t, tbl)
 	if !FullNextDate {
 		return
 	}
@@ -70,10 +55,45 @@ func TestNextDate(t *testing.T) {
 		{"20240222", "m -2,-3", ""},
 		{"20240326", "m -1,-2", "20240330"},
 		{"20240201", "m -1,18", "20240218"},
+		{"20240126", "m 30 2", ""},
+		{"20240126", "m 31 4,6,9,11", ""},
+		{"20240126", "m 31,30 2,4", "20240430"},
+		{"20240301", "m 29 2", "20280229"},
 		{"20240125", "w 1,2,3", "20240129"},
 		{"20240126", "w 7", "20240128"},
 		{"20230126", "w 4,5", "20240201"},
 		{"20230226", "w 8,4,5", ""},
 	}
-	check()
+	checkNextDate(t, tbl)
+}
+
+func checkNextDate(t *testing.T, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
+
+func TestNextDateEnd(t *testing.T) {
+	tbl := []nextDate{
+		{"20240120", "until 20240131", ""},
+		{"20240120", "d 7 until 2024", ""},
+		{"20240120", "d 7 count 0", ""},
+		{"20240120", "d 7 count x", ""},
+		{"20240120", "d 7 until 20240126", ""},
+		{"20240120", "d 7 until 20240127", "20240127"},
+		{"20240120", "d 7 count 3", "20240127"},
+		{"20240120", "y until 20251231", "20250120"},
+		{"20240120", "d 7 count 3 until 20240131", "20240127"},
+	}
+	checkNextDate(t, tbl)
 }
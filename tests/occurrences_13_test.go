@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type occurrencesResp struct {
+	Dates []string `json:"dates"`
+	Error string   `json:"error"`
+}
+
+func getOccurrences(t *testing.T, date, repeat, query string) occurrencesResp {
+	body, err := getBody("api/occurrences?now=20240126&date=" + date +
+		"&repeat=" + url.QueryEscape(repeat) + query)
+	assert.NoError(t, err)
+	var resp occurrencesResp
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	return resp
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		date   string
+		repeat string
+		query  string
+		want   []string
+	}{
+		{"20240126", "d 7", "&count=3", []string{"20240126", "20240202", "20240209"}},
+		{"20240120", "w 1,5", "&count=3", []string{"20240129", "20240202", "20240205"}},
+		{"20240130", "", "", []string{"20240130"}},
+		{"20240126", "d 7 count 2", "", []string{"20240126", "20240202"}},
+		{"20240126", "d 7 until 20240203", "", []string{"20240126", "20240202"}},
+		{"20240126", "d 7", "&until=20240205", []string{"20240126", "20240202"}},
+		{"20240126", "m 31", "&count=4", []string{"20240126", "20240131", "20240331", "20240531"}},
+	}
+	for _, v := range tbl {
+		resp := getOccurrences(t, v.date, v.repeat, v.query)
+		assert.Empty(t, resp.Error)
+		assert.Equal(t, v.want, resp.Dates, "%s %q %s", v.date, v.repeat, v.query)
+	}
+
+	resp := getOccurrences(t, "20240126", "d 1", "&count=1000")
+	assert.Equal(t, 100, len(resp.Dates))
+
+	for _, repeat := range []string{"m 32", "d 7 count x", "ooops"} {
+		resp = getOccurrences(t, "20240126", repeat, "")
+		assert.NotEmpty(t, resp.Error, repeat)
+		assert.Empty(t, resp.Dates, repeat)
+	}
+	resp = getOccurrences(t, "20240126", "d 7", "&count=0")
+	assert.NotEmpty(t, resp.Error)
+}
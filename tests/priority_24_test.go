@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPriority(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	add := func(date, title string, priority int) string {
+		status, _, m := matchRequest(t, http.MethodPost, "api/task", "",
+			map[string]any{"date": date, "title": title, "priority": priority})
+		assert.Equal(t, http.StatusCreated, status)
+		id, _ := m["id"].(string)
+		return id
+	}
+	gamma := add("20310101", "Гамма", 1)
+	alpha := add("20310101", "Альфа", 3)
+	beta := add("20310102", "Бета", 0)
+	delta := add("20310101", "Дельта", 3)
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, alpha))
+	assert.Equal(t, 3, task.Priority)
+	_, err := time.Parse("2006-01-02T15:04:05Z", task.Created)
+	assert.NoError(t, err, task.Created)
+
+	const period = "from=20310101&to=20310102&"
+
+	// По умолчанию — по дате, времени и id
+	page := getTasksPage(t, period)
+	assert.Equal(t, []string{gamma, alpha, delta, beta}, page.ids())
+	assert.Equal(t, float64(1), page.Tasks[0]["priority"])
+	assert.NotEmpty(t, page.Tasks[0]["created"])
+
+	// Задачи с одинаковым приоритетом упорядочены по дате и id
+	assert.Equal(t, []string{alpha, delta, gamma, beta}, getTasksPage(t, period+"sort=priority").ids())
+	assert.Equal(t, []string{alpha, beta, gamma, delta}, getTasksPage(t, period+"sort=title").ids())
+
+	page = getTasksPage(t, period+"sort=created")
+	if assert.Len(t, page.Tasks, 4) {
+		for i := 1; i < len(page.Tasks); i++ {
+			assert.GreaterOrEqual(t, page.Tasks[i-1]["created"], page.Tasks[i]["created"])
+		}
+	}
+
+	// Страницы при сортировке по приоритету
+	page = getTasksPage(t, period+"sort=priority&limit=3")
+	assert.Equal(t, []string{alpha, delta, gamma}, page.ids())
+	if assert.NotEmpty(t, page.NextCursor) {
+		page = getTasksPage(t, period+"sort=priority&limit=3&cursor="+page.NextCursor)
+		assert.Equal(t, []string{beta}, page.ids())
+		assert.Empty(t, page.NextCursor)
+	}
+
+	// Страница продолжается после ключа сортировки и id последней задачи,
+	// поэтому задача, добавленная перед позицией курсора, не сдвигает следующую страницу
+	page = getTasksPage(t, period+"sort=title&limit=2")
+	assert.Equal(t, []string{alpha, beta}, page.ids())
+	aero := add("20310102", "Аэро", 0)
+	if assert.NotEmpty(t, page.NextCursor) {
+		assert.Equal(t, []string{gamma, delta}, getTasksPage(t, period+"sort=title&limit=2&cursor="+page.NextCursor).ids())
+
+		// Курсор одной сортировки не подходит для другой
+		status, m := errorResp(t, http.MethodGet, "api/tasks?"+period+"sort=created&cursor="+page.NextCursor, nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "cursor", m["field"])
+	}
+	var listed []string
+	for cursor := "start"; cursor != ""; {
+		query := period + "sort=created&limit=1"
+		if cursor != "start" {
+			query += "&cursor=" + cursor
+		}
+		page = getTasksPage(t, query)
+		listed = append(listed, page.ids()...)
+		cursor = page.NextCursor
+	}
+	assert.ElementsMatch(t, []string{alpha, beta, gamma, delta, aero}, listed)
+
+	// PUT меняет приоритет, момент создания сохраняется
+	status, _, _ := matchRequest(t, http.MethodPut, "api/task", "",
+		map[string]any{"id": beta, "date": "20310102", "title": "Бета", "priority": 2})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{alpha, delta, beta, gamma, aero}, getTasksPage(t, period+"sort=priority").ids())
+	var created string
+	assert.NoError(t, db.Get(&created, `SELECT created_at FROM scheduler WHERE id=?`, beta))
+	assert.NotEmpty(t, created)
+
+	// PUT без поля priority (так редактирует веб-интерфейс) приоритет не меняет
+	status, _, _ = matchRequest(t, http.MethodPut, "api/task", "",
+		map[string]any{"id": alpha, "date": "20310101", "title": "Альфа и омега"})
+	assert.Equal(t, http.StatusOK, status)
+	_, _, m := matchRequest(t, http.MethodGet, "api/task?id="+alpha, "", nil)
+	assert.Equal(t, "Альфа и омега", m["title"])
+	assert.Equal(t, float64(3), m["priority"])
+
+	for _, v := range []map[string]any{
+		{"title": "Слишком срочно", "priority": 4},
+		{"title": "Отрицательный приоритет", "priority": -1},
+	} {
+		status, m := errorResp(t, http.MethodPost, "api/task", v)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "priority", m["field"])
+	}
+	status, m = errorResp(t, http.MethodGet, "api/tasks?sort=urgency", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "sort", m["field"])
+
+	for _, id := range []string{alpha, beta, gamma, delta, aero} {
+		status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+		assert.Equal(t, http.StatusOK, status)
+	}
+}
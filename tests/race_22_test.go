@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// parallelDone одновременно отправляет n запросов POST /api/task/done и возвращает
+// количество ответов с каждым кодом
+func parallelDone(t *testing.T, id, ifMatch string, n int) map[int]int {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses = map[int]int{}
+		start    = make(chan struct{})
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			status, _, _ := matchRequest(t, http.MethodPost, "api/task/done?id="+id, ifMatch, nil)
+			mu.Lock()
+			statuses[status]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return statuses
+}
+
+// historyNextDates возвращает отсортированные следующие даты из истории выполнения задачи
+func historyNextDates(t *testing.T, id string) []string {
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	var dates []string
+	for _, c := range m["completions"] {
+		dates = append(dates, c["next_date"])
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+func TestDoneRace(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	const n = 20
+	id := addTask(t, task{
+		date:   "20300101",
+		title:  "Ежедневная зарядка",
+		repeat: "d 1",
+	})
+
+	// Каждое выполнение переносит задачу ровно на один день от даты, записанной предыдущим
+	statuses := parallelDone(t, id, "", n)
+	assert.Equal(t, map[int]int{http.StatusOK: n}, statuses)
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "20300121", task.Date)
+	assert.Equal(t, 1+n, task.Version)
+
+	dates := historyNextDates(t, id)
+	if assert.Len(t, dates, n) {
+		for i, date := range dates {
+			// Все следующие даты разные: ни одно повторение не пропущено и не засчитано дважды
+			assert.Equal(t, time.Date(2030, 1, 2+i, 0, 0, 0, 0, time.UTC).Format(`20060102`), date)
+		}
+	}
+
+	status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestDoneRaceCount(t *testing.T) {
+	const n = 20
+	id := addTask(t, task{
+		date:   "20300101",
+		title:  "Курс из пяти занятий",
+		repeat: "d 7 count 5",
+	})
+
+	// Серия заканчивается после пятого выполнения, остальные запросы задачу уже не находят
+	statuses := parallelDone(t, id, "", n)
+	assert.Equal(t, map[int]int{http.StatusOK: 5, http.StatusNotFound: n - 5}, statuses)
+	notFoundTask(t, id)
+
+	assert.Equal(t, []string{"", "20300108", "20300115", "20300122", "20300129"}, historyNextDates(t, id))
+}
+
+func TestDoneRaceIfMatch(t *testing.T) {
+	const n = 10
+	id := addTask(t, task{
+		date:   "20300101",
+		title:  "Продлить подписку",
+		repeat: "d 30",
+	})
+
+	// С одной и той же версией в If-Match задачу можно выполнить только один раз
+	statuses := parallelDone(t, id, `"1"`, n)
+	assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusPreconditionFailed: n - 1}, statuses)
+	assert.Equal(t, []string{"20300131"}, historyNextDates(t, id))
+
+	status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+}
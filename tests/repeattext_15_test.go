@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type repeatTextTask struct {
+	ID         string `json:"id"`
+	RepeatText string `json:"repeat_text"`
+}
+
+func getWithLang(t *testing.T, apipath, lang string, v any) {
+	req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+	assert.NoError(t, err)
+	if len(lang) > 0 {
+		req.Header.Set("Accept-Language", lang)
+	}
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(body, v))
+}
+
+func TestRepeatText(t *testing.T) {
+	future := testNow().AddDate(0, 0, 2).Format(`20060102`)
+
+	tbl := []struct {
+		repeat string
+		lang   string
+		want   string
+	}{
+		{"m 1,-1 3,6,9,12", "", "в 1-й и последний день марта, июня, сентября и декабря"},
+		{"m 1,-1 3,6,9,12", "en-US,en;q=0.9", "on the 1st and last day of March, June, September and December"},
+		{"m 1,-1 3,6,9,12", "de;q=1, en;q=0.5, ru;q=0.8", "в 1-й и последний день марта, июня, сентября и декабря"},
+		{"d 1", "ru", "каждый день"},
+		{"d 3", "ru", "каждые 3 дня"},
+		{"d 21", "ru", "каждый 21 день"},
+		{"d 11", "en", "every 11 days"},
+		{"b 5 count 3", "ru", "каждые 5 рабочих дней, 3 раза"},
+		{"w 1,3,5", "ru", "по понедельникам, средам и пятницам"},
+		{"w 1,3,5", "en", "every Monday, Wednesday and Friday"},
+		{"mw 2:2,-1:5", "ru", "во второй вторник и в последнюю пятницу каждого месяца"},
+		{"mw 2:2,-1:5", "en", "on the second Tuesday and last Friday of every month"},
+		{"m 22 shift until 20261231", "en",
+			"on the 22nd day of every month, moved to the next business day if it falls on a day off, until December 31, 2026"},
+		{"y", "en", "every year"},
+		{"y shift", "ru", "каждый год, с переносом на следующий рабочий день, если дата выпадает на выходной"},
+	}
+	for _, v := range tbl {
+		id := addTask(t, task{date: future, title: "Описание правила", repeat: v.repeat})
+
+		var tsk repeatTextTask
+		getWithLang(t, "api/task?id="+id, v.lang, &tsk)
+		assert.Equal(t, v.want, tsk.RepeatText, "%q %q", v.repeat, v.lang)
+	}
+
+	id := addTask(t, task{date: future, title: "Описание правила в списке", repeat: "d 2"})
+	var list struct {
+		Tasks []repeatTextTask `json:"tasks"`
+	}
+	getWithLang(t, "api/tasks?limit=500", "en", &list)
+	found := false
+	for _, tsk := range list.Tasks {
+		if tsk.ID == id {
+			found = true
+			assert.Equal(t, "every 2 days", tsk.RepeatText)
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatRule(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	future := testNow().AddDate(0, 0, 3).Format(`20060102`)
+
+	// Некорректное правило не сохраняется, даже если дата в будущем
+	for _, repeat := range []string{"m 40", "w 0", "d 7 8", "y 1", "ooops", "d 7 count 0"} {
+		m, err := postJSON("api/task", map[string]any{
+			"date":   future,
+			"title":  "Правило",
+			"repeat": repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для правила %q", repeat)
+	}
+
+	// Правило можно передать в структурированном виде
+	m, err := postJSON("api/task", map[string]any{
+		"date":  future,
+		"title": "Правило",
+		"repeat_rule": map[string]any{
+			"kind":            "mw",
+			"month_week_days": []map[string]int{{"nth": 2, "week_day": 2}, {"nth": -1, "week_day": 5}},
+			"months":          []int{3, 6},
+			"count":           4,
+		},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	id := fmt.Sprint(m["id"])
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "mw 2:2,-1:5 3,6 count 4", task.Repeat)
+	assert.Equal(t, 4, task.Remaining)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var resp struct {
+		Repeat     string `json:"repeat"`
+		RepeatRule struct {
+			Kind   string `json:"kind"`
+			Months []int  `json:"months"`
+			Count  int    `json:"count"`
+		} `json:"repeat_rule"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	assert.Equal(t, "mw 2:2,-1:5 3,6 count 4", resp.Repeat)
+	assert.Equal(t, "mw", resp.RepeatRule.Kind)
+	assert.Equal(t, []int{3, 6}, resp.RepeatRule.Months)
+	assert.Equal(t, 4, resp.RepeatRule.Count)
+
+	// Обновление с некорректным правилом отклоняется
+	m, err = postJSON("api/task", map[string]any{
+		"id":     id,
+		"date":   future,
+		"title":  "Правило",
+		"repeat": "m 10 shift 3",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+
+	// Строка repeat и repeat_rule из ответа GET должны совпадать: очищенный repeat
+	// с прежним repeat_rule отклоняется, а не сохраняет старое правило
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(body, &got))
+	got["repeat"] = ""
+	m, err = postJSON("api/task", got, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "mw 2:2,-1:5 3,6 count 4", task.Repeat)
+
+	// Без repeat_rule пустой repeat убирает повторение
+	delete(got, "repeat_rule")
+	m, err = postJSON("api/task", got, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Empty(t, task.Repeat)
+
+	// Совпадающие repeat и repeat_rule принимаются
+	got["repeat"] = "m 10"
+	got["repeat_rule"] = map[string]any{"kind": "m", "month_days": []int{10}}
+	m, err = postJSON("api/task", got, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, "m 10", task.Repeat)
+}
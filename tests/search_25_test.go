@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchPage выполняет текстовый поиск в диапазоне дат теста с дополнительными параметрами
+func searchPage(t *testing.T, search, query string) tasksPage {
+	return getTasksPage(t, "from=20320101&to=20320131&search="+url.QueryEscape(search)+query)
+}
+
+func TestSearch(t *testing.T) {
+	report := addTask(t, task{date: "20320105", title: "Годовой отчёт квазара",
+		comment: "собрать цифры у бухгалтерии и отправить директору до конца недели"})
+	reverse := addTask(t, task{date: "20320108", title: "Отчёт годовой", comment: "черновик"})
+	quasar := addTask(t, task{date: "20320110", title: "Квазар квазар квазар"})
+	xss := addTask(t, task{date: "20320112", title: `Квазар <img src=x onerror=alert(1)>`,
+		comment: `"кавычки" & 'апострофы'`})
+
+	// Слова ищутся по префиксу и без учёта регистра, в том числе в кириллице
+	assert.Equal(t, []string{report, reverse}, searchPage(t, "отч", "&order=date").ids())
+	assert.Equal(t, []string{report, reverse}, searchPage(t, "ОТЧЁТ ГОД", "&order=date").ids())
+	assert.Equal(t, []string{report, quasar, xss}, searchPage(t, "КВАЗ", "&order=date").ids())
+
+	// Фраза в кавычках ищется целиком и с тем же порядком слов
+	assert.Equal(t, []string{report}, searchPage(t, `"годовой отчёт"`, "").ids())
+	assert.Equal(t, []string{reverse}, searchPage(t, `"отчёт годовой"`, "").ids())
+
+	// По релевантности выше задача, где слово встречается чаще и текст короче;
+	// order=date возвращает те же задачи в порядке дат
+	ranked := searchPage(t, "квазар", "").ids()
+	if assert.Len(t, ranked, 3) {
+		assert.Equal(t, quasar, ranked[0])
+		assert.Equal(t, report, ranked[2])
+	}
+	assert.Equal(t, ranked, searchPage(t, "квазар", "&order=rank").ids())
+	assert.Equal(t, []string{report, quasar, xss}, searchPage(t, "квазар", "&order=date").ids())
+
+	// В сниппете текст задачи экранирован, теги <mark> выделяют только совпадения
+	page := searchPage(t, "onerror", "")
+	if assert.Len(t, page.Tasks, 1) {
+		assert.Equal(t, xss, page.ids()[0])
+		assert.Equal(t, "Квазар &lt;img src=x <mark>onerror</mark>=alert(1)&gt;", page.Tasks[0]["snippet"])
+	}
+	page = searchPage(t, "апострофы", "")
+	if assert.Len(t, page.Tasks, 1) {
+		assert.Equal(t, "&#34;кавычки&#34; &amp; &#39;<mark>апострофы</mark>&#39;", page.Tasks[0]["snippet"])
+	}
+
+	// Без поиска сниппета нет
+	page = getTasksPage(t, "from=20320101&to=20320131")
+	if assert.NotEmpty(t, page.Tasks) {
+		assert.Nil(t, page.Tasks[0]["snippet"])
+	}
+
+	for _, id := range []string{report, reverse, quasar, xss} {
+		status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+		assert.Equal(t, http.StatusOK, status)
+	}
+}
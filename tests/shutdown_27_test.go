@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"final_project/pkg/db"
+	"final_project/pkg/server"
+)
+
+// shutdownPort — порт отдельного сервера для проверки остановки, не совпадает с портом основного
+const shutdownPort = "7551"
+
+// waitListening ждёт, пока сервер начнёт принимать соединения
+func waitListening(t *testing.T, addr string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("сервер не начал слушать %s", addr)
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "scheduler.db")
+	t.Setenv("TODO_DBFILE", file)
+	t.Setenv("TODO_PORT", shutdownPort)
+	t.Setenv("TODO_SHUTDOWN_TIMEOUT", "5s")
+
+	store, err := db.Init()
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- server.Run(ctx, "../web", store) }()
+
+	addr := "localhost:" + shutdownPort
+	waitListening(t, addr)
+
+	// Запрос начат до остановки: заголовки отправлены, тело передаётся частями
+	body := `{"date": "20300101", "title": "Дописать отчёт"}`
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, "http://"+addr+"/api/task", pr)
+	assert.NoError(t, err)
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	_, err = io.WriteString(pw, body[:10])
+	assert.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// После отмены контекста сервер перестаёт принимать соединения, но ждёт начатый запрос
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-runErr:
+		t.Fatalf("сервер остановился, не дождавшись запроса: %v", err)
+	default:
+	}
+
+	_, err = io.WriteString(pw, body[10:])
+	assert.NoError(t, err)
+	assert.NoError(t, pw.Close())
+	assert.Equal(t, http.StatusCreated, <-status)
+
+	select {
+	case err := <-runErr:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("сервер не остановился")
+	}
+
+	// Хранилище закрыто, задача из начатого запроса сохранена
+	_, err = store.GetTask("1")
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "closed"), err.Error())
+	}
+	conn, err := sqlx.Connect("sqlite", file)
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		var title string
+		assert.NoError(t, conn.Get(&title, `SELECT title FROM scheduler`))
+		assert.Equal(t, "Дописать отчёт", title)
+	}
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signIn(t *testing.T, password string) (int, map[string]string) {
+	data, err := json.Marshal(map[string]string{"password": password})
+	assert.NoError(t, err)
+
+	resp, err := http.Post(getURL("api/signin"), "application/json", bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+
+	var m map[string]string
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return resp.StatusCode, m
+}
+
+func requestWithToken(t *testing.T, apipath, token string) int {
+	req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+	assert.NoError(t, err)
+	if len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestSignIn(t *testing.T) {
+	password := os.Getenv("TODO_PASSWORD")
+	if len(password) == 0 {
+		t.Skip("TODO_PASSWORD не задан, аутентификация отключена")
+	}
+
+	status, m := signIn(t, password+"ooops")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.NotEmpty(t, m["error"])
+
+	status, m = signIn(t, password)
+	assert.Equal(t, http.StatusOK, status)
+	token := m["token"]
+	assert.NotEmpty(t, token)
+
+	// В полезной нагрузке токена только срок действия, ничего производного от пароля
+	parts := strings.Split(token, ".")
+	if assert.Len(t, parts, 3) {
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.NoError(t, err)
+		var claims map[string]any
+		assert.NoError(t, json.Unmarshal(payload, &claims))
+		assert.Len(t, claims, 1)
+		assert.Contains(t, claims, "exp")
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(t, "api/tasks", ""))
+	assert.Equal(t, http.StatusUnauthorized, requestWithToken(t, "api/tasks", token+"x"))
+	assert.Equal(t, http.StatusOK, requestWithToken(t, "api/tasks", token))
+}
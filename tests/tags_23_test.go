@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tagCounts возвращает количество задач для каждой метки из ответа /api/tags
+func tagCounts(t *testing.T) map[string]int {
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Tags []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+
+	counts := map[string]int{}
+	for _, tag := range m.Tags {
+		counts[tag.Name] = tag.Count
+	}
+	return counts
+}
+
+// addTagged добавляет задачу с метками и возвращает её id
+func addTagged(t *testing.T, title string, tags ...string) string {
+	status, _, m := matchRequest(t, http.MethodPost, "api/task",
+		"", map[string]any{"date": "20300101", "title": title, "tags": tags})
+	assert.Equal(t, http.StatusCreated, status)
+	id, _ := m["id"].(string)
+	return id
+}
+
+func TestTags(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// Метки приводятся к нижнему регистру, повторы убираются
+	work := addTagged(t, "Разобрать почту", " Work23 ", "work23")
+	both := addTagged(t, "Созвон по дежурству", "oncall23", "work23")
+	home := addTagged(t, "Вынести мусор", "home23")
+
+	_, _, m := matchRequest(t, http.MethodGet, "api/task?id="+work, "", nil)
+	assert.Equal(t, []any{"work23"}, m["tags"])
+	_, _, m = matchRequest(t, http.MethodGet, "api/task?id="+both, "", nil)
+	assert.Equal(t, []any{"oncall23", "work23"}, m["tags"])
+
+	assert.ElementsMatch(t, []string{work, both}, getTasksPage(t, "tag=work23").ids())
+	assert.ElementsMatch(t, []string{both}, getTasksPage(t, "tag=work23&tag=oncall23").ids())
+	assert.ElementsMatch(t, []string{both}, getTasksPage(t, "tag=WORK23,oncall23&tag_mode=and").ids())
+	assert.ElementsMatch(t, []string{both, home}, getTasksPage(t, "tag=oncall23,home23&tag_mode=or").ids())
+	assert.Empty(t, getTasksPage(t, "tag=home23&tag=work23").Tasks)
+
+	assert.Equal(t, 2, tagCounts(t)["work23"])
+	assert.Equal(t, 1, tagCounts(t)["oncall23"])
+
+	// PUT заменяет метки целиком
+	status, _, _ := matchRequest(t, http.MethodPut, "api/task", "",
+		map[string]any{"id": both, "date": "20300101", "title": "Созвон по дежурству", "tags": []string{"oncall23"}})
+	assert.Equal(t, http.StatusOK, status)
+	assert.ElementsMatch(t, []string{work}, getTasksPage(t, "tag=work23").ids())
+	assert.Equal(t, 1, tagCounts(t)["work23"])
+
+	// PUT без поля tags (так редактирует веб-интерфейс) метки не меняет, пустой массив их удаляет
+	status, _, _ = matchRequest(t, http.MethodPut, "api/task", "",
+		map[string]any{"id": work, "date": "20300101", "title": "Разобрать почту за неделю"})
+	assert.Equal(t, http.StatusOK, status)
+	_, _, m = matchRequest(t, http.MethodGet, "api/task?id="+work, "", nil)
+	assert.Equal(t, "Разобрать почту за неделю", m["title"])
+	assert.Equal(t, []any{"work23"}, m["tags"])
+
+	status, _, _ = matchRequest(t, http.MethodPut, "api/task", "",
+		map[string]any{"id": both, "date": "20300101", "title": "Созвон по дежурству", "tags": []string{}})
+	assert.Equal(t, http.StatusOK, status)
+	_, _, m = matchRequest(t, http.MethodGet, "api/task?id="+both, "", nil)
+	assert.Nil(t, m["tags"])
+	_, ok := tagCounts(t)["oncall23"]
+	assert.False(t, ok)
+
+	for _, v := range []map[string]any{
+		{"title": "Метка с запятой", "tags": []string{"a,b"}},
+		{"title": "Пустая метка", "tags": []string{" "}},
+	} {
+		status, m := errorResp(t, http.MethodPost, "api/task", v)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "tags", m["field"])
+	}
+	status, m = errorResp(t, http.MethodGet, "api/tasks?tag=work23&tag_mode=xor", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "tag_mode", m["field"])
+
+	// Удаление задачи удаляет её связи с метками, метка без задач пропадает из списка
+	undo := undoToken(t, "api/task?id="+home, http.MethodDelete)
+	var links int
+	assert.NoError(t, db.Get(&links, `SELECT count(*) FROM task_tags WHERE task_id = ?`, home))
+	assert.Equal(t, 0, links)
+	_, ok = tagCounts(t)["home23"]
+	assert.False(t, ok)
+
+	// Отмена удаления возвращает задаче метки
+	_, err := postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{home}, getTasksPage(t, "tag=home23").ids())
+
+	for _, id := range []string{work, both, home} {
+		status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Empty(t, getTasksPage(t, "tag=work23,oncall23,home23&tag_mode=or").Tasks)
+	var orphans int
+	assert.NoError(t, db.Get(&orphans, `SELECT count(*) FROM tags WHERE name IN ('work23', 'oncall23', 'home23')`))
+	assert.Equal(t, 0, orphans)
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,7 +22,7 @@ func TestDone(t *testing.T) {
 	db := openDB(t)
 	defer db.Close()
 
-	now := time.Now()
+	now := testNow()
 	id := addTask(t, task{
 		date:  now.Format(`20060102`),
 		title: "Свести баланс",
@@ -73,3 +72,233 @@ func TestDelTask(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, ret)
 }
+
+func TestDoneHistory(t *testing.T) {
+	now := testNow()
+	id := addTask(t, task{
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	history := m["completions"]
+	assert.Equal(t, 2, len(history))
+	if len(history) == 2 {
+		// Записи идут от последней к первой
+		assert.Equal(t, now.Format(`20060102`), history[1]["date"])
+		assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), history[1]["next_date"])
+		assert.Equal(t, history[1]["next_date"], history[0]["date"])
+		assert.Equal(t, "Полить цветы", history[0]["title"])
+	}
+
+	today := now.Format(`20060102`)
+	body, err = requestJSON("api/completions?from="+today+"&to="+today, nil, http.MethodGet)
+	assert.NoError(t, err)
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	found := 0
+	for _, v := range m["completions"] {
+		if v["task_id"] == id {
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+}
+
+func undoToken(t *testing.T, apipath, method string) string {
+	req, err := http.NewRequest(method, getURL(apipath), nil)
+	assert.NoError(t, err)
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	return resp.Header.Get("X-Undo-Token")
+}
+
+// assertRestored проверяет, что отмена вернула задаче прежние значения
+// Восстановление — новое изменение задачи, поэтому её версия только растёт
+func assertRestored(t *testing.T, before, after Task) {
+	assert.Greater(t, after.Version, before.Version)
+	after.Version = before.Version
+	assert.Equal(t, before, after)
+}
+
+func TestUndo(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := testNow()
+	id := addTask(t, task{
+		date:    now.Format(`20060102`),
+		title:   "Купить хлеб",
+		comment: "бородинский",
+	})
+
+	var before Task
+	err := db.Get(&before, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+
+	undo := undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	assert.NotEmpty(t, undo)
+	notFoundTask(t, id)
+
+	ret, err := postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, id, ret["id"])
+
+	var after Task
+	err = db.Get(&after, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assertRestored(t, before, after)
+
+	// Повторно токен использовать нельзя
+	ret, err = postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	undo = undoToken(t, "api/task?id="+id, http.MethodDelete)
+	assert.NotEmpty(t, undo)
+	notFoundTask(t, id)
+
+	_, err = postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	err = db.Get(&after, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assertRestored(t, before, after)
+
+	// Снимок пишется вместе с операцией: неудавшееся удаление снимка не оставляет
+	snapshots := func() int {
+		var n int
+		assert.NoError(t, db.Get(&n, `SELECT count(*) FROM task_undo WHERE task_id = ?`, id))
+		return n
+	}
+	status, _, _ := matchRequest(t, http.MethodDelete, "api/task?id="+id, `"1"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, 0, snapshots())
+	undo = undoToken(t, "api/task?id="+id, http.MethodDelete)
+	assert.Equal(t, 1, snapshots())
+	_, err = postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, snapshots())
+
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Вынести мусор",
+		repeat: "d 2",
+	})
+	err = db.Get(&before, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+
+	undo = undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	_, err = postJSON("api/task/undo?token="+undo, nil, http.MethodPost)
+	assert.NoError(t, err)
+	err = db.Get(&after, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assertRestored(t, before, after)
+}
+
+func TestDoneEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := testNow()
+	id := addTask(t, task{
+		title:  "Принять таблетку",
+		repeat: "d 1 count 2",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	var tsk Task
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), tsk.Date)
+	assert.Equal(t, 1, tsk.Remaining)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	id = addTask(t, task{
+		title:  "Курс упражнений",
+		repeat: "d 3 until " + now.AddDate(0, 0, 4).Format(`20060102`),
+	})
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	err = db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), tsk.Date)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
+
+func TestRepeatCountKept(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		title:  "Курс массажа",
+		repeat: "d 1 count 3",
+	})
+	remaining := func() int {
+		var tsk Task
+		assert.NoError(t, db.Get(&tsk, `SELECT * FROM scheduler WHERE id=?`, id))
+		return tsk.Remaining
+	}
+
+	_, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, remaining())
+
+	// Правка правила без изменения count продолжает серию, новое число повторений начинает её заново
+	put := func(repeat string, extra map[string]any) {
+		values := map[string]any{"id": id, "title": "Курс массажа", "repeat": repeat}
+		for k, v := range extra {
+			values[k] = v
+		}
+		status, _, _ := matchRequest(t, http.MethodPut, "api/task", "", values)
+		assert.Equal(t, http.StatusOK, status)
+	}
+	put("d 2 count 3", map[string]any{"remaining": 3})
+	assert.Equal(t, 2, remaining())
+	put("d 2 count 5", nil)
+	assert.Equal(t, 5, remaining())
+
+	// Выгрузка содержит счётчик, импорт его сохраняет
+	_, _, m := matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, float64(5), m["remaining"])
+	status, resp := postFile(t, "api/import?format=json&mode=upsert", "application/json",
+		`[{"id": "`+id+`", "date": "`+m["date"].(string)+`", "title": "Курс массажа", "repeat": "d 2 count 5", "remaining": 4}]`)
+	assert.Equal(t, http.StatusOK, status, resp)
+	assert.Equal(t, 4, remaining())
+
+	status, resp = postFile(t, "api/import?format=csv&mode=upsert", "text/csv",
+		"id,title,repeat,remaining\n"+id+",Курс массажа,d 2 count 5,6\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, float64(1), resp["errors"])
+	assert.Equal(t, 4, remaining())
+
+	status, _, _ = matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,7 +23,7 @@ func addTask(t *testing.T, task task) string {
 	return id
 }
 
-func getTasks(t *testing.T, search string) []map[string]string {
+func getTasks(t *testing.T, search string) []map[string]any {
 	url := "api/tasks"
 	if Search {
 		url += "?search=" + search
@@ -32,17 +31,19 @@ func getTasks(t *testing.T, search string) []map[string]string {
 	body, err := requestJSON(url, nil, http.MethodGet)
 	assert.NoError(t, err)
 
-	var m map[string][]map[string]string
+	var m struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
 	err = json.Unmarshal(body, &m)
 	assert.NoError(t, err)
-	return m["tasks"]
+	return m.Tasks
 }
 
 func TestTasks(t *testing.T) {
 	db := openDB(t)
 	defer db.Close()
 
-	now := time.Now()
+	now := testNow()
 	_, err := db.Exec("DELETE FROM scheduler")
 	assert.NoError(t, err)
 
@@ -106,3 +107,103 @@ func TestTasks(t *testing.T) {
 	assert.Equal(t, 3, len(tasks))
 
 }
+
+type tasksPage struct {
+	Tasks      []map[string]any `json:"tasks"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int              `json:"total"`
+}
+
+func getTasksPage(t *testing.T, query string) tasksPage {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var page tasksPage
+	err = json.Unmarshal(body, &page)
+	assert.NoError(t, err)
+	return page
+}
+
+// ids возвращает id задач страницы в порядке списка
+func (p tasksPage) ids() []string {
+	ids := []string{}
+	for _, task := range p.Tasks {
+		ids = append(ids, fmt.Sprint(task["id"]))
+	}
+	return ids
+}
+
+func TestTasksPages(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := testNow()
+	for i := 0; i < 5; i++ {
+		repeat := ""
+		if i%2 == 0 {
+			repeat = "d 7"
+		}
+		addTask(t, task{
+			date:   now.AddDate(0, 0, i/2).Format(`20060102`),
+			title:  fmt.Sprintf("Задача %d", i),
+			repeat: repeat,
+		})
+	}
+
+	seen := map[string]bool{}
+	cursor := ""
+	pages := 0
+	for {
+		page := getTasksPage(t, "limit=2&cursor="+cursor)
+		assert.Equal(t, 5, page.Total)
+		for _, v := range page.Tasks {
+			id := fmt.Sprint(v["id"])
+			assert.False(t, seen[id], "задача %s повторяется на разных страницах", id)
+			seen[id] = true
+		}
+		pages++
+		if page.NextCursor == "" || pages > 5 {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, 5, len(seen))
+
+	page := getTasksPage(t, "repeat=yes")
+	assert.Equal(t, 3, page.Total)
+	page = getTasksPage(t, "repeat=no")
+	assert.Equal(t, 2, page.Total)
+
+	date := now.AddDate(0, 0, 1).Format(`20060102`)
+	page = getTasksPage(t, "from="+date+"&to="+date)
+	assert.Equal(t, 2, len(page.Tasks))
+
+	page = getTasksPage(t, "overdue=true")
+	assert.Equal(t, 0, page.Total)
+
+	body, err := requestJSON("api/tasks?limit=0", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "error")
+
+	// Курсор поиска по релевантности (смещение) и курсор списка не взаимозаменяемы:
+	// пропущенный курсор вернул бы первую страницу снова
+	listed := getTasksPage(t, "limit=2")
+	ranked := getTasksPage(t, "search=Задача&limit=2")
+	if assert.NotEmpty(t, listed.NextCursor) && assert.NotEmpty(t, ranked.NextCursor) {
+		for _, query := range []string{
+			"limit=2&cursor=" + ranked.NextCursor,
+			"limit=2&sort=title&cursor=" + ranked.NextCursor,
+			"search=Задача&order=date&limit=2&cursor=" + ranked.NextCursor,
+			"search=Задача&limit=2&cursor=" + listed.NextCursor,
+		} {
+			status, m := errorResp(t, http.MethodGet, "api/tasks?"+query, nil)
+			assert.Equal(t, http.StatusBadRequest, status, query)
+			assert.Equal(t, "cursor", m["field"], query)
+		}
+		assert.NotEmpty(t, getTasksPage(t, "search=Задача&limit=2&cursor="+ranked.NextCursor).Tasks)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTimedTask(t *testing.T, values map[string]any) string {
+	m, err := postJSON("api/task", values, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"], "%v", values)
+	return fmt.Sprint(m["id"])
+}
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := testNow().AddDate(0, 0, 400).Format(`20060102`)
+
+	for _, v := range []map[string]any{
+		{"time": "25:00"},
+		{"time": "ooops"},
+		{"time": "10:00", "duration": -5},
+		{"time": "10:00", "duration": 24*60 + 1},
+		{"duration": 15},
+	} {
+		v["date"], v["title"] = date, "Время"
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, m["error"], "Ожидается ошибка для задачи %v", v)
+	}
+
+	late := addTimedTask(t, map[string]any{"date": date, "title": "Поздняя", "time": "10:00"})
+	allDay := addTimedTask(t, map[string]any{"date": date, "title": "Весь день"})
+	early := addTimedTask(t, map[string]any{"date": date, "title": "Стендап",
+		"time": "9:05", "duration": 15, "repeat": "d 1"})
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, early))
+	assert.Equal(t, "09:05", task.Time)
+	assert.Equal(t, 15, task.Duration)
+
+	// В пределах дня задачи на весь день идут первыми, затем по времени
+	want := []string{allDay, early, late}
+	page := getTasksPage(t, "from="+date+"&to="+date)
+	var ids []string
+	for _, v := range page.Tasks {
+		ids = append(ids, fmt.Sprint(v["id"]))
+	}
+	assert.Equal(t, want, ids)
+
+	// Постраничный вывод сохраняет тот же порядок
+	ids, cursor := nil, ""
+	for i := 0; i < len(want); i++ {
+		page = getTasksPage(t, "limit=1&from="+date+"&to="+date+"&cursor="+cursor)
+		for _, v := range page.Tasks {
+			ids = append(ids, fmt.Sprint(v["id"]))
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, want, ids)
+	assert.Empty(t, cursor)
+
+	// При выполнении периодической задачи время и длительность сохраняются
+	ret, err := postJSON("api/task/done?id="+early, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, early))
+	assert.NotEqual(t, date, task.Date)
+	assert.Equal(t, "09:05", task.Time)
+	assert.Equal(t, 15, task.Duration)
+
+	if feedToken := os.Getenv("TODO_CALENDAR_TOKEN"); len(feedToken) > 0 || len(os.Getenv("TODO_PASSWORD")) == 0 {
+		body, err := getBody("api/calendar.ics?token=" + url.QueryEscape(feedToken))
+		assert.NoError(t, err)
+		ics := string(body)
+		uid := "UID:task-" + early + "@final_project\r\n"
+		if assert.Contains(t, ics, uid) {
+			event := ics[strings.Index(ics, uid):]
+			event = event[:strings.Index(event, "END:VEVENT")]
+			assert.Contains(t, event, "DTSTART:"+task.Date+"T090500\r\n")
+			assert.Contains(t, event, "DURATION:PT15M\r\n")
+		}
+	}
+
+	// PUT без полей time и duration (так редактирует веб-интерфейс) время и длительность не меняет
+	m, err := postJSON("api/task", map[string]any{
+		"id": early, "date": task.Date, "title": "Стендап команды", "repeat": "d 1",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, early))
+	assert.Equal(t, "Стендап команды", task.Title)
+	assert.Equal(t, "09:05", task.Time)
+	assert.Equal(t, 15, task.Duration)
+
+	// С пустым временем задача снова становится задачей на весь день
+	m, err = postJSON("api/task", map[string]any{
+		"id": late, "date": date, "title": "Поздняя", "time": "",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m["error"])
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, late))
+	assert.Equal(t, "", task.Time)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nearMidnight — 00:30 26 января по Москве, но ещё 25 января в UTC и Нью-Йорке
+const nearMidnight = "2024-01-25T21:30:00Z"
+
+func getInTimezone(t *testing.T, apipath, tz string) (int, string) {
+	req, err := http.NewRequest(http.MethodGet, getURL(apipath), nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Timezone", tz)
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, strings.TrimSpace(string(body))
+}
+
+func TestTimezoneNextDate(t *testing.T) {
+	tbl := []struct {
+		now    string
+		tz     string
+		date   string
+		repeat string
+		want   string
+	}{
+		{nearMidnight, "Europe/Moscow", "20240125", "d 1", "20240127"},
+		{nearMidnight, "UTC", "20240125", "d 1", "20240126"},
+		{nearMidnight, "America/New_York", "20240125", "d 1", "20240126"},
+		{nearMidnight, "Europe/Moscow", "20240101", "m 26", "20240226"},
+		{nearMidnight, "UTC", "20240101", "m 26", "20240126"},
+		{nearMidnight, "Europe/Moscow", "20240119", "w 5", "20240202"},
+		{nearMidnight, "UTC", "20240119", "w 5", "20240126"},
+		{"2024-01-26T00:30:00+03:00", "UTC", "20240125", "d 1", "20240126"},
+		{"20240126", "America/New_York", "20240125", "d 1", "20240127"},
+	}
+	for _, v := range tbl {
+		code, next := getInTimezone(t, "api/nextdate?now="+url.QueryEscape(v.now)+
+			"&date="+v.date+"&repeat="+url.QueryEscape(v.repeat), v.tz)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, v.want, next, "%s %s %s %q", v.now, v.tz, v.date, v.repeat)
+	}
+
+	code, _ := getInTimezone(t, "api/nextdate?date=20240126&repeat=d+1", "Mars/Olympus")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestTimezoneToday(t *testing.T) {
+	for tz, want := range map[string]string{
+		"Europe/Moscow":    "20240126",
+		"UTC":              "20240125",
+		"America/New_York": "20240125",
+	} {
+		// Дата в прошлом без повторения переносится на «сегодня» в поясе пользователя
+		code, body := getInTimezone(t, "api/occurrences?date=20240125&now="+url.QueryEscape(nearMidnight), tz)
+		assert.Equal(t, http.StatusOK, code)
+		var resp occurrencesResp
+		assert.NoError(t, json.Unmarshal([]byte(body), &resp))
+		assert.Equal(t, []string{want}, resp.Dates, tz)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// matchRequest выполняет запрос с заголовком If-Match (если он не пустой)
+// и возвращает код ответа, заголовок ETag и разобранное тело
+func matchRequest(t *testing.T, method, apipath, ifMatch string, values map[string]any) (int, string, map[string]any) {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	if token := getToken(); len(token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	var m map[string]any
+	if len(bytes.TrimSpace(body)) > 0 {
+		assert.NoError(t, json.Unmarshal(body, &m))
+	}
+	return resp.StatusCode, resp.Header.Get("ETag"), m
+}
+
+func TestTaskVersion(t *testing.T) {
+	id := addTask(t, task{
+		date:  "20300101",
+		title: "Согласовать отпуск",
+	})
+
+	status, etag, m := matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"1"`, etag)
+	assert.Equal(t, float64(1), m["version"])
+
+	update := map[string]any{"id": id, "date": "20300102", "title": "Согласовать отпуск с командой"}
+	status, etag, _ = matchRequest(t, http.MethodPut, "api/task", `"1"`, update)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"2"`, etag)
+
+	// Второй клиент прочитал задачу до изменения: его правка отклоняется
+	update["title"] = "Перенести отпуск"
+	status, _, m = matchRequest(t, http.MethodPut, "api/task", `"1"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, "precondition_failed", m["code"])
+
+	_, _, m = matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, "Согласовать отпуск с командой", m["title"])
+
+	// ETag сравниваются строго: слабый ETag не совпадает даже с текущей версией
+	status, _, _ = matchRequest(t, http.MethodPut, "api/task", `W/"2"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+
+	// Из списка ETag достаточно совпадения одного сильного
+	status, _, _ = matchRequest(t, http.MethodPut, "api/task", `"1", W/"2"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	status, etag, _ = matchRequest(t, http.MethodPut, "api/task", `"1", W/"2", "2"`, update)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"3"`, etag)
+
+	// * тоже принимается, без If-Match задача перезаписывается как раньше
+	status, etag, _ = matchRequest(t, http.MethodPut, "api/task", "*", update)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"4"`, etag)
+	status, etag, _ = matchRequest(t, http.MethodPut, "api/task", "", update)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"5"`, etag)
+
+	for _, ifMatch := range []string{"5", `"5" "6"`, `"5",`, `"5`} {
+		status, _, m = matchRequest(t, http.MethodPut, "api/task", ifMatch, update)
+		assert.Equal(t, http.StatusBadRequest, status, ifMatch)
+		assert.Equal(t, "If-Match", m["field"], ifMatch)
+	}
+
+	status, _, m = matchRequest(t, http.MethodPut, "api/task", `"5"`,
+		map[string]any{"id": "999999999", "title": "Нет такой задачи"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, "not_found", m["code"])
+
+	// Удаление и выполнение тоже проверяют версию
+	status, _, _ = matchRequest(t, http.MethodDelete, "api/task?id="+id, `"4"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	status, _, _ = matchRequest(t, http.MethodPost, "api/task/done?id="+id, `"4"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	status, _, _ = matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, _ = matchRequest(t, http.MethodDelete, "api/task?id="+id, `"4", "5"`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	notFoundTask(t, id)
+}
+
+func TestDoneVersion(t *testing.T) {
+	id := addTask(t, task{
+		date:   "20300101",
+		title:  "Полить цветы",
+		repeat: "d 2",
+	})
+
+	status, etag, _ := matchRequest(t, http.MethodPost, "api/task/done?id="+id, `"1"`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"2"`, etag)
+
+	// Повторное выполнение по устаревшей версии не переносит задачу ещё раз
+	status, _, _ = matchRequest(t, http.MethodPost, "api/task/done?id="+id, `"1"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+
+	_, etag, m := matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, `"2"`, etag)
+	assert.Equal(t, "20300103", m["date"])
+
+	// Новая версия в ETag совпадает с сохранённой, в том числе после правок задачи
+	status, etag, _ = matchRequest(t, http.MethodPut, "api/task", etag,
+		map[string]any{"id": id, "date": "20300103", "title": "Полить цветы", "repeat": "d 2"})
+	assert.Equal(t, http.StatusOK, status)
+	status, etag, _ = matchRequest(t, http.MethodPost, "api/task/done?id="+id, etag, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"4"`, etag)
+	_, etag, _ = matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, `"4"`, etag)
+
+	status, _, _ = matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Разовая задача удаляется при выполнении, и ETag не передаётся
+	id = addTask(t, task{date: "20300101", title: "Сдать показания"})
+	status, etag, _ = matchRequest(t, http.MethodPost, "api/task/done?id="+id, `"1"`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, etag)
+	notFoundTask(t, id)
+}
+
+func TestUndoVersion(t *testing.T) {
+	id := addTask(t, task{
+		date:   "20300101",
+		title:  "Проверить показания счётчиков",
+		repeat: "d 7",
+	})
+
+	// После выполнения задачу изменил другой клиент: отмена не затирает его правку
+	undo := undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	status, _, _ := matchRequest(t, http.MethodPut, "api/task", `"2"`,
+		map[string]any{"id": id, "date": "20300108", "title": "Передать показания счётчиков", "repeat": "d 7"})
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, m := matchRequest(t, http.MethodPost, "api/task/undo?token="+undo, "", nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assert.Equal(t, "precondition_failed", m["code"])
+	_, etag, m := matchRequest(t, http.MethodGet, "api/task?id="+id, "", nil)
+	assert.Equal(t, `"3"`, etag)
+	assert.Equal(t, "Передать показания счётчиков", m["title"])
+	assert.Equal(t, "20300108", m["date"])
+
+	// Без изменений после операции отмена проходит
+	undo = undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	status, etag, m = matchRequest(t, http.MethodPost, "api/task/undo?token="+undo, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `"5"`, etag)
+	assert.Equal(t, "20300108", m["date"])
+
+	// Удалённую задачу отмена возвращает, пока задачи с тем же id нет
+	undo = undoToken(t, "api/task?id="+id, http.MethodDelete)
+	status, _, _ = matchRequest(t, http.MethodPost, "api/task/undo?token="+undo, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _, _ = matchRequest(t, http.MethodDelete, "api/task?id="+id, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+}
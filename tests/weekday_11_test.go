@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+)
+
+func TestNextDateMonthWeekDay(t *testing.T) {
+	if !FullNextDate {
+		return
+	}
+	tbl := []nextDate{
+		{"20240126", "mw", ""},
+		{"20240126", "mw 2", ""},
+		{"20240126", "mw 0:1", ""},
+		{"20240126", "mw 6:1", ""},
+		{"20240126", "mw -6:1", ""},
+		{"20240126", "mw 2:8", ""},
+		{"20240126", "mw 2:0", ""},
+		{"20240126", "mw 2:2 13", ""},
+		{"20240126", "mw 2:2 1 ooops", ""},
+		{"20240126", "mw 2:2", "20240213"},
+		{"20240126", "mw -1:5", "20240223"},
+		{"20240126", "mw 2:2,-1:5", "20240213"},
+		{"20240126", "mw -1:7", "20240128"},
+		{"20240126", "mw 5:4", "20240229"},
+		{"20240126", "mw 1:1 3,6", "20240304"},
+		{"20240301", "mw 1:5", "20240405"},
+		{"20240126", "mw 5:1 2", ""},
+		{"20240126", "mw -5:3 2", ""},
+		{"20240126", "mw 5:1,-1:1 2", "20240226"},
+		{"20240430", "mw 5:1 4", "20290430"},
+		{"20230115", "mw -2:1 12", "20241223"},
+	}
+	checkNextDate(t, tbl)
+}